@@ -1,5 +1,37 @@
 #include "_cgo_export.h"
 
-JSValue InvokeProxy(JSContext *ctx, JSValueConst this_val, int argc, JSValueConst *argv) {
-	 return proxy(ctx, this_val, argc, argv);
-}
\ No newline at end of file
+JSValue InvokeProxy(JSContext *ctx, JSValueConst this_val, int argc, JSValueConst *argv, int magic, JSValue *func_data) {
+	 return proxy(ctx, this_val, argc, argv, magic, func_data);
+}
+
+int InvokeInterruptHandler(JSRuntime *rt, void *opaque) {
+	 return interruptHandler(rt, opaque);
+}
+
+void InvokeFuncPtrFinalizer(JSRuntime *rt, JSValue val) {
+	 funcPtrFinalizer(rt, val);
+}
+
+void InvokeGoValueFinalizer(JSRuntime *rt, JSValue val) {
+	 goValueFinalizer(rt, val);
+}
+
+void InvokeGoClassFinalizer(JSRuntime *rt, JSValue val) {
+	 goClassFinalizer(rt, val);
+}
+
+void InvokeArrayBufferFree(JSRuntime *rt, void *opaque, void *ptr) {
+	 arrayBufferFree(rt, opaque);
+}
+
+char *InvokeModuleNormalize(JSContext *ctx, const char *module_base_name, const char *module_name, void *opaque) {
+	 return moduleNormalize(ctx, (char *)module_base_name, (char *)module_name);
+}
+
+JSModuleDef *InvokeModuleLoader(JSContext *ctx, const char *module_name, void *opaque) {
+	 return moduleLoader(ctx, (char *)module_name);
+}
+
+int InvokeNativeModuleInit(JSContext *ctx, JSModuleDef *m) {
+	 return nativeModuleInit(ctx, m);
+}
@@ -1,8 +1,52 @@
+#ifndef _GUARD_H_BRIDGE_H_
+#define _GUARD_H_BRIDGE_H_
+
 #include "stdlib.h"
 #include "quickjs.h"
 #include "list.h"
 
-extern JSValue InvokeProxy(JSContext *ctx, JSValueConst this_val, int argc, JSValueConst *argv);
+extern JSValue InvokeProxy(JSContext *ctx, JSValueConst this_val, int argc, JSValueConst *argv, int magic, JSValue *func_data);
+extern int InvokeInterruptHandler(JSRuntime *rt, void *opaque);
+extern void InvokeFuncPtrFinalizer(JSRuntime *rt, JSValue val);
+extern void InvokeGoValueFinalizer(JSRuntime *rt, JSValue val);
+extern void InvokeGoClassFinalizer(JSRuntime *rt, JSValue val);
+extern void InvokeArrayBufferFree(JSRuntime *rt, void *opaque, void *ptr);
+extern char *InvokeModuleNormalize(JSContext *ctx, const char *module_base_name, const char *module_name, void *opaque);
+extern JSModuleDef *InvokeModuleLoader(JSContext *ctx, const char *module_name, void *opaque);
+extern int InvokeNativeModuleInit(JSContext *ctx, JSModuleDef *m);
+
+static void SetInterruptHandler(JSRuntime *rt, uintptr_t handle) { JS_SetInterruptHandler(rt, InvokeInterruptHandler, (void *)handle); }
+static void ClearInterruptHandler(JSRuntime *rt) { JS_SetInterruptHandler(rt, NULL, NULL); }
+
+static void SetModuleLoader(JSRuntime *rt) { JS_SetModuleLoaderFunc(rt, InvokeModuleNormalize, InvokeModuleLoader, NULL); }
+static void ClearModuleLoader(JSRuntime *rt) { JS_SetModuleLoaderFunc(rt, NULL, NULL, NULL); }
+static JSModuleDef *NewNativeModule(JSContext *ctx, const char *name) { return JS_NewCModule(ctx, name, InvokeNativeModuleInit); }
+
+static int NewFuncPtrClass(JSRuntime *rt, JSClassID class_id)
+{
+    JSClassDef def = {"GoFunction", .finalizer = InvokeFuncPtrFinalizer};
+    return JS_NewClass(rt, class_id, &def);
+}
+
+static int NewGoValueClass(JSRuntime *rt, JSClassID class_id)
+{
+    JSClassDef def = {"GoValue", .finalizer = InvokeGoValueFinalizer};
+    return JS_NewClass(rt, class_id, &def);
+}
+
+static int NewGoClass(JSRuntime *rt, JSClassID class_id, const char *class_name)
+{
+    JSClassDef def = {class_name, .finalizer = InvokeGoClassFinalizer};
+    return JS_NewClass(rt, class_id, &def);
+}
+
+static void SetOpaqueID(JSValue obj, int64_t id) { JS_SetOpaque(obj, (void *)(intptr_t)id); }
+static int64_t GetOpaqueID(JSValueConst obj, JSClassID class_id) { return (int64_t)(intptr_t)JS_GetOpaque(obj, class_id); }
+
+static JSValue NewArrayBufferNoCopy(JSContext *ctx, void *buf, size_t len, int64_t id)
+{
+    return JS_NewArrayBuffer(ctx, (uint8_t *)buf, len, InvokeArrayBufferFree, (void *)(intptr_t)id, 0);
+}
 
 static JSValue JS_NewNull() { return JS_NULL; }
 static JSValue JS_NewUndefined() { return JS_UNDEFINED; }
@@ -14,6 +58,8 @@ static JSValue ThrowReferenceError(JSContext *ctx, const char *fmt) { return JS_
 static JSValue ThrowRangeError(JSContext *ctx, const char *fmt) { return JS_ThrowRangeError(ctx, "%s", fmt); }
 static JSValue ThrowInternalError(JSContext *ctx, const char *fmt) { return JS_ThrowInternalError(ctx, "%s", fmt); }
 
+static void *GetValuePtr(JSValueConst v) { return JS_VALUE_GET_PTR(v); }
+
 static int GetValueRefCount(JSContext *ctx, JSValue v)
 {
     if (JS_VALUE_HAS_REF_COUNT(v))
@@ -36,4 +82,6 @@ static JS_BOOL JS_IsFloat64Number(JSValueConst v)
 {
     int tag = JS_VALUE_GET_TAG(v);
     return JS_TAG_IS_FLOAT64(tag);
-}
\ No newline at end of file
+}
+
+#endif
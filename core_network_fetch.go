@@ -46,7 +46,9 @@ func jsRequest(ctx *Context, this Value, args []Value) Value {
 	url := args[0].String()
 	init := &FetchInit{}
 	if len(args) > 1 {
-		args[1].Decode(init)
+		if err := args[1].Decode(init); err != nil {
+			return ctx.ThrowError(err)
+		}
 	}
 	if len(init.Method) == 0 {
 		init.Method = "GET"
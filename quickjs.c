@@ -5074,11 +5074,13 @@ static JSValue js_c_function_data_call(JSContext *ctx, JSValueConst func_obj,
                                        JSValueConst this_val,
                                        int argc, JSValueConst *argv, int flags)
 {
+    JSRuntime *rt = ctx->rt;
     JSCFunctionDataRecord *s = JS_GetOpaque(func_obj, JS_CLASS_C_FUNCTION_DATA);
+    JSStackFrame sf_s, *sf = &sf_s;
     JSValueConst *arg_buf;
+    JSValue ret_val;
     int i;
 
-    /* XXX: could add the function on the stack for debug */
     if (unlikely(argc < s->length)) {
         arg_buf = alloca(sizeof(arg_buf[0]) * s->length);
         for(i = 0; i < argc; i++)
@@ -5089,7 +5091,18 @@ static JSValue js_c_function_data_call(JSContext *ctx, JSValueConst func_obj,
         arg_buf = argv;
     }
 
-    return s->func(ctx, this_val, argc, arg_buf, s->magic, s->data);
+    /* add the function on the stack so that it appears in the backtrace */
+    sf->prev_frame = rt->current_stack_frame;
+    sf->js_mode = 0;
+    sf->cur_func = (JSValue)func_obj;
+    sf->arg_count = max_int(argc, s->length);
+    sf->arg_buf = (JSValue *)arg_buf;
+    rt->current_stack_frame = sf;
+
+    ret_val = s->func(ctx, this_val, argc, arg_buf, s->magic, s->data);
+
+    rt->current_stack_frame = sf->prev_frame;
+    return ret_val;
 }
 
 JSValue JS_NewCFunctionData(JSContext *ctx, JSCFunctionData *func,
@@ -5785,6 +5798,89 @@ void JS_RunGC(JSRuntime *rt)
     gc_free_cycles(rt);
 }
 
+/* collect the GC objects referenced from outside of the runtime (by the
+   application or by the live contexts), at most 'max' entries are stored
+   in 'refs'. Return the number of such objects. */
+int JS_GetExternalRefs(JSRuntime *rt, JSExternalRef *refs, int max)
+{
+    struct list_head *el;
+    JSGCObjectHeader *p;
+    JSExternalRef *r;
+    int count;
+
+    JS_RunGC(rt);
+
+    /* remove the internal refcounts, as done when dumping the leaks */
+    gc_decref(rt);
+    count = 0;
+    list_for_each(el, &rt->gc_obj_list) {
+        p = list_entry(el, JSGCObjectHeader, link);
+        if (p->ref_count == 0)
+            continue;
+        if (count < max) {
+            r = &refs[count];
+            r->ptr = p;
+            r->gc_obj_type = p->gc_obj_type;
+            r->ref_count = p->ref_count;
+            r->class_name[0] = '\0';
+            if (p->gc_obj_type == JS_GC_OBJ_TYPE_JS_OBJECT) {
+                char buf[ATOM_GET_STR_BUF_SIZE];
+                snprintf(r->class_name, sizeof(r->class_name), "%s",
+                         JS_AtomGetStrRT(rt, buf, sizeof(buf),
+                                         rt->class_array[((JSObject *)p)->class_id].class_name));
+            }
+        }
+        count++;
+    }
+    /* restore the refcounts */
+    gc_scan(rt);
+    gc_free_cycles(rt);
+    return count;
+}
+
+/* free the exception and the pending jobs as JS_FreeRuntime() does, so
+   that the objects they reference are not reported as external */
+void JS_FreeRuntimePending(JSRuntime *rt)
+{
+    struct list_head *el, *el1;
+    int i;
+
+    JS_FreeValueRT(rt, rt->current_exception);
+    rt->current_exception = JS_NULL;
+
+    list_for_each_safe(el, el1, &rt->job_list) {
+        JSJobEntry *e = list_entry(el, JSJobEntry, link);
+        for(i = 0; i < e->argc; i++)
+            JS_FreeValueRT(rt, e->argv[i]);
+        js_free_rt(rt, e);
+    }
+    init_list_head(&rt->job_list);
+}
+
+/* create a private symbol, the properties keyed by it are invisible to
+   javascript, e.g. Object.getOwnPropertySymbols() */
+JSValue JS_NewPrivateSymbol(JSContext *ctx, const char *description)
+{
+    JSAtom descr;
+    JSValue sym;
+
+    descr = JS_NewAtom(ctx, description);
+    if (descr == JS_ATOM_NULL)
+        return JS_EXCEPTION;
+    sym = JS_NewSymbolFromAtom(ctx, descr, JS_ATOM_TYPE_PRIVATE);
+    JS_FreeAtom(ctx, descr);
+    return sym;
+}
+
+/* collect the cycles and abandon the objects which are still referenced
+   externally, so that JS_FreeRuntime() does not abort. The abandoned
+   objects are not finalized and their memory is not released. */
+void JS_AbandonGCObjects(JSRuntime *rt)
+{
+    JS_RunGC(rt);
+    init_list_head(&rt->gc_obj_list);
+}
+
 /* Return false if not an object or if the object has already been
    freed (zombie objects are visible in finalizers when freeing
    cycles). */
@@ -6545,6 +6641,13 @@ JSValue JS_NewError(JSContext *ctx)
     return JS_NewObjectClass(ctx, JS_CLASS_ERROR);
 }
 
+/* set the 'stack' property of the error object created by JS_NewError()
+   to the backtrace of the current call */
+void JS_SetErrorBacktrace(JSContext *ctx, JSValueConst error_obj)
+{
+    build_backtrace(ctx, error_obj, NULL, 0, 0);
+}
+
 static JSValue JS_ThrowError2(JSContext *ctx, JSErrorEnum error_num,
                               const char *fmt, va_list ap, BOOL add_backtrace)
 {
@@ -9760,6 +9863,16 @@ void *JS_GetOpaque(JSValueConst obj, JSClassID class_id)
     return p->u.opaque;
 }
 
+/* return 0 if not an object */
+JSClassID JS_GetClassID(JSValueConst obj)
+{
+    JSObject *p;
+    if (JS_VALUE_GET_TAG(obj) != JS_TAG_OBJECT)
+        return 0;
+    p = JS_VALUE_GET_OBJ(obj);
+    return p->class_id;
+}
+
 void *JS_GetOpaque2(JSContext *ctx, JSValueConst obj, JSClassID class_id)
 {
     void *p = JS_GetOpaque(obj, class_id);
@@ -28050,6 +28163,27 @@ JSAtom JS_GetModuleName(JSContext *ctx, JSModuleDef *m)
     return JS_DupAtom(ctx, m->module_name);
 }
 
+/* return the namespace object of the module */
+JSValue JS_GetModuleNamespace(JSContext *ctx, JSModuleDef *m)
+{
+    return js_get_module_ns(ctx, m);
+}
+
+/* return the latest module successfully evaluated with the name or NULL */
+JSModuleDef *JS_FindEvaluatedModule(JSContext *ctx, JSAtom name)
+{
+    struct list_head *el;
+    JSModuleDef *m, *found;
+
+    found = NULL;
+    list_for_each(el, &ctx->loaded_modules) {
+        m = list_entry(el, JSModuleDef, link);
+        if (m->module_name == name && m->evaluated && !m->eval_has_exception)
+            found = m;
+    }
+    return found;
+}
+
 JSValue JS_GetImportMeta(JSContext *ctx, JSModuleDef *m)
 {
     JSValue obj;
@@ -45307,6 +45441,16 @@ typedef struct JSMapState {
 #define MAGIC_SET (1 << 0)
 #define MAGIC_WEAK (1 << 1)
 
+JS_BOOL JS_IsMap(JSValueConst obj)
+{
+    return JS_GetClassID(obj) == JS_CLASS_MAP;
+}
+
+JS_BOOL JS_IsSet(JSValueConst obj)
+{
+    return JS_GetClassID(obj) == JS_CLASS_SET;
+}
+
 static JSValue js_map_constructor(JSContext *ctx, JSValueConst new_target,
                                   int argc, JSValueConst *argv, int magic)
 {
@@ -47820,6 +47964,20 @@ static double time_clip(double t) {
         return NAN;
 }
 
+JSValue JS_NewDate(JSContext *ctx, double epoch_ms)
+{
+    JSValue obj = js_create_from_ctor(ctx, JS_UNDEFINED, JS_CLASS_DATE);
+    if (JS_IsException(obj))
+        return JS_EXCEPTION;
+    JS_SetObjectData(ctx, obj, __JS_NewFloat64(ctx, time_clip(epoch_ms)));
+    return obj;
+}
+
+JS_BOOL JS_IsDate(JSValueConst v)
+{
+    return JS_GetClassID(v) == JS_CLASS_DATE;
+}
+
 /* The spec mandates the use of 'double' and it fixes the order
    of the operations */
 static double set_date_fields(double fields[], int is_local) {
@@ -49530,6 +49688,28 @@ static JSValue js_bigfloat_constructor(JSContext *ctx,
     return JS_EXCEPTION;
 }
 
+/* create a BigInt from the string as the intrinsic BigInt() does, the
+   global BigInt which could be overwritten by javascript is not used */
+JSValue JS_NewBigIntFromString(JSContext *ctx, const char *str)
+{
+    JSValue s = JS_NewString(ctx, str);
+    if (JS_IsException(s))
+        return s;
+    return JS_ToBigIntCtorFree(ctx, s);
+}
+
+/* create a BigFloat from the string as the intrinsic BigFloat() does */
+JSValue JS_NewBigFloatFromString(JSContext *ctx, const char *str)
+{
+    JSValue s, ret;
+    s = JS_NewString(ctx, str);
+    if (JS_IsException(s))
+        return s;
+    ret = js_bigfloat_constructor(ctx, JS_UNDEFINED, 1, (JSValueConst *)&s);
+    JS_FreeValue(ctx, s);
+    return ret;
+}
+
 static JSValue js_bigfloat_get_const(JSContext *ctx,
                                      JSValueConst this_val, int magic)
 {
@@ -52907,6 +53087,41 @@ static JSValue js_typed_array_constructor(JSContext *ctx,
     return obj;
 }
 
+JSValue JS_NewTypedArray(JSContext *ctx, JSValueConst buffer,
+                         JSTypedArrayEnum type)
+{
+    JSValueConst args[3];
+    if (type < JS_TYPED_ARRAY_UINT8C || type > JS_TYPED_ARRAY_FLOAT64)
+        return JS_ThrowRangeError(ctx, "invalid typed array type");
+    args[0] = buffer;
+    args[1] = JS_NewInt32(ctx, 0);
+    args[2] = JS_UNDEFINED;
+    return js_typed_array_constructor(ctx, JS_UNDEFINED, 3, args,
+                                      JS_CLASS_UINT8C_ARRAY + type);
+}
+
+/* return -1 if obj is not a typed array */
+int JS_GetTypedArrayType(JSValueConst obj)
+{
+    JSClassID class_id = JS_GetClassID(obj);
+    if (class_id >= JS_CLASS_UINT8C_ARRAY &&
+        class_id <= JS_CLASS_FLOAT64_ARRAY)
+        return class_id - JS_CLASS_UINT8C_ARRAY;
+    return -1;
+}
+
+JS_BOOL JS_IsArrayBuffer(JSValueConst obj)
+{
+    JSClassID class_id = JS_GetClassID(obj);
+    return class_id == JS_CLASS_ARRAY_BUFFER ||
+        class_id == JS_CLASS_SHARED_ARRAY_BUFFER;
+}
+
+JS_BOOL JS_IsDataView(JSValueConst obj)
+{
+    return JS_GetClassID(obj) == JS_CLASS_DATAVIEW;
+}
+
 static void js_typed_array_finalizer(JSRuntime *rt, JSValue val)
 {
     JSObject *p = JS_VALUE_GET_OBJ(val);
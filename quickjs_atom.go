@@ -24,5 +24,5 @@ func (a Atom) String() string {
 }
 
 func (a Atom) Value() Value {
-	return Value{ctx: a.ctx, ref: C.JS_AtomToValue(a.ctx.ref, a.ref)}
+	return a.ctx.newValue(C.JS_AtomToValue(a.ctx.ref, a.ref))
 }
@@ -0,0 +1,164 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// typed array types of the golang slice element kinds
+var typedArrayTypes = map[reflect.Kind]C.JSTypedArrayEnum{
+	reflect.Int8:    C.JS_TYPED_ARRAY_INT8,
+	reflect.Uint8:   C.JS_TYPED_ARRAY_UINT8,
+	reflect.Int16:   C.JS_TYPED_ARRAY_INT16,
+	reflect.Uint16:  C.JS_TYPED_ARRAY_UINT16,
+	reflect.Int32:   C.JS_TYPED_ARRAY_INT32,
+	reflect.Uint32:  C.JS_TYPED_ARRAY_UINT32,
+	reflect.Int64:   C.JS_TYPED_ARRAY_BIG_INT64,
+	reflect.Uint64:  C.JS_TYPED_ARRAY_BIG_UINT64,
+	reflect.Float32: C.JS_TYPED_ARRAY_FLOAT32,
+	reflect.Float64: C.JS_TYPED_ARRAY_FLOAT64,
+}
+
+// golang slice types of the typed array types, indexed by JSTypedArrayEnum
+var typedArraySliceTypes = []reflect.Type{
+	C.JS_TYPED_ARRAY_UINT8C:     reflect.TypeOf([]uint8(nil)),
+	C.JS_TYPED_ARRAY_INT8:       reflect.TypeOf([]int8(nil)),
+	C.JS_TYPED_ARRAY_UINT8:      reflect.TypeOf([]uint8(nil)),
+	C.JS_TYPED_ARRAY_INT16:      reflect.TypeOf([]int16(nil)),
+	C.JS_TYPED_ARRAY_UINT16:     reflect.TypeOf([]uint16(nil)),
+	C.JS_TYPED_ARRAY_INT32:      reflect.TypeOf([]int32(nil)),
+	C.JS_TYPED_ARRAY_UINT32:     reflect.TypeOf([]uint32(nil)),
+	C.JS_TYPED_ARRAY_BIG_INT64:  reflect.TypeOf([]int64(nil)),
+	C.JS_TYPED_ARRAY_BIG_UINT64: reflect.TypeOf([]uint64(nil)),
+	C.JS_TYPED_ARRAY_FLOAT32:    reflect.TypeOf([]float32(nil)),
+	C.JS_TYPED_ARRAY_FLOAT64:    reflect.TypeOf([]float64(nil)),
+}
+
+//export arrayBufferFree
+func arrayBufferFree(rt *C.JSRuntime, opaque unsafe.Pointer) {
+	id := int64(uintptr(opaque))
+	// the memory of ArrayBufferNoCopy is unpinned
+	if pinner, ok := restoreHandle(id); ok {
+		pinner.(interface{ Unpin() }).Unpin()
+	}
+	freeHandle(id)
+}
+
+// ArrayBuffer create ArrayBuffer with the copy of data
+func (ctx *Context) ArrayBuffer(data []byte) Value {
+	if len(data) == 0 {
+		return ctx.newValue(C.JS_NewArrayBufferCopy(ctx.ref, nil, 0))
+	}
+	return ctx.newValue(C.JS_NewArrayBufferCopy(ctx.ref, (*C.uint8_t)(unsafe.Pointer(&data[0])), C.size_t(len(data))))
+}
+
+// TypedArray create typed array with the copy of golang slice,
+// []int8, []uint8, []int16, []uint16, []int32, []uint32, []int64, []uint64, []float32 and []float64 are supported,
+// e.g. []float64 will be Float64Array and []int64 will be BigInt64Array
+func (ctx *Context) TypedArray(slice interface{}) (Value, error) {
+	reflectValue := reflect.ValueOf(slice)
+	if reflectValue.Kind() != reflect.Slice {
+		return ctx.Undefined(), fmt.Errorf("can not create typed array from '%T'", slice)
+	}
+	typedArrayType, ok := typedArrayTypes[reflectValue.Type().Elem().Kind()]
+	if !ok {
+		return ctx.Undefined(), fmt.Errorf("can not create typed array from '%T'", slice)
+	}
+	val := ctx.typedArray(reflectValue, typedArrayType)
+	if val.IsException() {
+		return val, ctx.Exception()
+	}
+	return val, nil
+}
+
+func (ctx *Context) typedArray(slice reflect.Value, typedArrayType C.JSTypedArrayEnum) Value {
+	buffer := ctx.ArrayBuffer(sliceBytes(slice))
+	defer buffer.Free()
+	if buffer.IsException() {
+		return buffer
+	}
+	return ctx.newValue(C.JS_NewTypedArray(ctx.ref, buffer.ref, typedArrayType))
+}
+
+// sliceBytes return the memory of slice as []byte, the elements must not contain pointers
+func sliceBytes(slice reflect.Value) []byte {
+	size := slice.Len() * int(slice.Type().Elem().Size())
+	if size == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(slice.Pointer())), size)
+}
+
+// cBytes return the memory of C as []byte without copy
+func cBytes(ptr *C.uint8_t, size C.size_t) []byte {
+	if ptr == nil || size == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(ptr)), int(size))
+}
+
+// bytes return the memory of ArrayBuffer, DataView or typed array without copy
+func (v Value) bytes() ([]byte, bool) {
+	switch {
+	case v.IsArrayBuffer():
+		var size C.size_t
+		ptr := C.JS_GetArrayBuffer(v.ctx.ref, &size, v.ref)
+		if ptr == nil {
+			// detached buffer
+			v.ctx.Exception()
+		}
+		return cBytes(ptr, size), true
+	case v.IsTypedArray():
+		var offset, size C.size_t
+		buffer := v.ctx.newValue(C.JS_GetTypedArrayBuffer(v.ctx.ref, v.ref, &offset, &size, nil))
+		if buffer.IsException() {
+			// detached buffer
+			v.ctx.Exception()
+			return nil, true
+		}
+		defer buffer.Free()
+		data, _ := buffer.bytes()
+		return data[offset : offset+size], true
+	case v.IsDataView():
+		buffer := v.Get("buffer")
+		defer buffer.Free()
+		data, _ := buffer.bytes()
+		offset, size := v.GetInt64("byteOffset"), v.GetInt64("byteLength")
+		if int64(len(data)) < offset+size {
+			return nil, true
+		}
+		return data[offset : offset+size], true
+	}
+	return nil, false
+}
+
+// Bytes return the copy of the bytes of ArrayBuffer, DataView or typed array (the viewed range only),
+// nil will be returned for other values
+func (v Value) Bytes() []byte {
+	data, ok := v.bytes()
+	if !ok {
+		return nil
+	}
+	return append(make([]byte, 0, len(data)), data...)
+}
+
+// typedArraySlice return the copy of typed array as golang slice,
+// Uint8Array and Uint8ClampedArray will be []byte, and Float64Array will be []float64 for example
+func (v Value) typedArraySlice() interface{} {
+	sliceType := typedArraySliceTypes[C.JS_GetTypedArrayType(v.ref)]
+	data, _ := v.bytes()
+	size := len(data) / int(sliceType.Elem().Size())
+	slice := reflect.MakeSlice(sliceType, size, size)
+	copy(sliceBytes(slice), data)
+	return slice.Interface()
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	stdruntime "runtime"
+	"unsafe"
+)
+
+// ArrayBufferNoCopy create ArrayBuffer which shares the memory of data without copy,
+// data is pinned until the ArrayBuffer is finalized, the writes from both sides are visible to each other,
+// so the length of data must not be changed (e.g. by append) while the ArrayBuffer is alive,
+// it requires go1.21 (runtime.Pinner)
+func (ctx *Context) ArrayBufferNoCopy(data []byte) Value {
+	if len(data) == 0 {
+		return ctx.ArrayBuffer(data)
+	}
+	// quickjs keeps the pointer after the call, so the memory must not be moved or collected
+	pinner := &stdruntime.Pinner{}
+	pinner.Pin(&data[0])
+	id := storeHandle(pinner)
+	return ctx.newValue(C.NewArrayBufferNoCopy(ctx.ref, unsafe.Pointer(&data[0]), C.size_t(len(data)), C.int64_t(id)))
+}
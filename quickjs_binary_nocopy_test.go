@@ -0,0 +1,49 @@
+//go:build go1.21
+
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+)
+
+func TestContext_ArrayBufferNoCopy(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	data := []byte{1, 2, 3, 4}
+	before := handleStoreLen()
+	shared := ctx.ArrayBufferNoCopy(data)
+	ctx.Globals().Set("shared", shared)
+	assert.Equal(before+1, handleStoreLen())
+
+	v, err := ctx.EvalGlobal(`
+new Uint8Array(shared)[1] = 20;
+shared.byteLength`)
+	require.NoError(t, err)
+	assert.Equal(int64(4), v.InterfaceAndFree())
+	assert.Equal([]byte{1, 20, 3, 4}, data)
+
+	// golang side writes are visible in javascript
+	data[3] = 40
+	v, err = ctx.EvalGlobal(`new DataView(shared, 2, 2)`)
+	require.NoError(t, err)
+	assert.True(v.IsDataView())
+	assert.Equal([]byte{3, 40}, v.InterfaceAndFree())
+
+	ctx.Globals().DeleteProperty("shared")
+	r.RunGC()
+	assert.Equal(before, handleStoreLen())
+
+	empty := ctx.ArrayBufferNoCopy(nil)
+	assert.Equal([]byte{}, empty.Bytes())
+	empty.Free()
+}
@@ -0,0 +1,102 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+)
+
+type DemoPacket struct {
+	Kind    string
+	Payload []byte
+}
+
+func TestContext_ArrayBuffer(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	data := []byte{1, 2, 3, 4}
+	copied := ctx.ArrayBuffer(data)
+	assert.True(copied.IsArrayBuffer())
+	ctx.Globals().Set("copied", copied)
+
+	v, err := ctx.EvalGlobal(`new Uint8Array(copied)[0] = 10; copied.byteLength`)
+	require.NoError(t, err)
+	assert.Equal(int64(4), v.InterfaceAndFree())
+	// the copy is not shared
+	assert.Equal([]byte{1, 2, 3, 4}, data)
+
+	empty := ctx.ArrayBuffer(nil)
+	assert.Equal([]byte{}, empty.Bytes())
+	empty.Free()
+
+	undefined := ctx.Undefined()
+	assert.Nil(undefined.Bytes())
+}
+
+func TestContext_TypedArray(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	for _, slice := range []interface{}{
+		[]int8{-1, 2}, []uint8{1, 2}, []int16{-1, 2}, []uint16{1, 2}, []int32{-1, 2}, []uint32{1, 2},
+		[]int64{-1, 2}, []uint64{1, 2}, []float32{1.5, 2}, []float64{1.5, 2},
+	} {
+		v, err := ctx.TypedArray(slice)
+		require.NoError(t, err)
+		assert.True(v.IsTypedArray())
+		assert.Equal(slice, v.InterfaceAndFree())
+	}
+
+	_, err := ctx.TypedArray([]string{"a"})
+	assert.Error(err)
+	_, err = ctx.TypedArray(1)
+	assert.Error(err)
+
+	v, err := ctx.TypedArray([]float64{1.5, 2.5, 3.5})
+	require.NoError(t, err)
+	ctx.Globals().Set("floats", v)
+	v, err = ctx.EvalGlobal(`[floats instanceof Float64Array, floats.reduce((a, b) => a + b), floats.subarray(1).byteLength]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{true, 7.5, int64(16)}, v.InterfaceAndFree())
+
+	v, err = ctx.EvalGlobal(`new Uint8Array([1, 2, 3, 4]).subarray(1, 3)`)
+	require.NoError(t, err)
+	assert.Equal([]byte{2, 3}, v.Bytes())
+	v.Free()
+
+	ctx.Globals().SetGoValue("packet", DemoPacket{Kind: "raw", Payload: []byte("hello")})
+	ctx.Globals().SetGoValue("upper", func(data []byte) []byte {
+		for i, b := range data {
+			if b >= 'a' && b <= 'z' {
+				data[i] = b - 'a' + 'A'
+			}
+		}
+		return data
+	})
+	v, err = ctx.EvalGlobal(`
+const upperPayload = upper(packet.Payload);
+[packet.Payload instanceof Uint8Array, String.fromCharCode(...upperPayload)]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{true, "HELLO"}, v.InterfaceAndFree())
+
+	v, err = ctx.EvalGlobal(`({ Kind: "raw", Payload: new Uint8Array([104, 105]) })`)
+	require.NoError(t, err)
+	defer v.Free()
+	packet := &DemoPacket{}
+	assert.NoError(v.Decode(packet))
+	assert.Equal(DemoPacket{Kind: "raw", Payload: []byte("hi")}, *packet)
+}
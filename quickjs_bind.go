@@ -0,0 +1,137 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Bind create a live binding of the golang struct pointer
+//
+// each exported field will be an accessor property of the returned object, which reads and writes the field directly,
+// so that the modification in javascript is visible in golang and vice versa,
+// the fields are named and flattened as Context.ToJSValue does,
+// the field tagged with `quickjs:",readonly"` (or not settable) has getter only,
+// the nested struct fields are bound too, and the exported methods of the pointer are defined on the object
+func (ctx *Context) Bind(ptr interface{}) (Value, error) {
+	reflectValue := reflect.ValueOf(ptr)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() || reflectValue.Elem().Kind() != reflect.Struct {
+		return ctx.Undefined(), fmt.Errorf("can not bind '%T', a non-nil struct pointer is required", ptr)
+	}
+	return ctx.bind(reflectValue), nil
+}
+
+func (ctx *Context) bind(ptr reflect.Value) Value {
+	obj := ctx.Object()
+	structValue := ptr.Elem()
+	structType := structValue.Type()
+
+	for _, f := range structFields(structType, ctx.namingPolicy) {
+		field, ok := fieldByIndex(structValue, f.index)
+		if !ok {
+			continue
+		}
+		setter := ctx.Undefined()
+		if !f.readonly && field.CanSet() {
+			setter = ctx.bindSetter(f.name, field)
+		}
+		nameAtom := ctx.Atom(f.name)
+		C.JS_DefinePropertyGetSet(ctx.ref, obj.ref, nameAtom.ref, ctx.bindGetter(f.name, field).transfer(), setter.transfer(), C.JS_PROP_ENUMERABLE)
+		nameAtom.Free()
+	}
+
+	ptrType := ptr.Type()
+	for mIndex := 0; mIndex < ptrType.NumMethod(); mIndex++ {
+		methodName := ctx.propertyName(ptrType.Method(mIndex).Name)
+		obj.defineProperty(methodName, ctx.reflectFunction(methodName, ptr.Method(mIndex)), C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	}
+
+	return obj
+}
+
+// bindGetter return the field value, the nested struct will be bound instead of copied,
+// the bound object is cached by the parent object until the pointer of the nested struct is changed
+func (ctx *Context) bindGetter(name string, field reflect.Value) Value {
+	return ctx.NamedFunction("get "+name, 0, func(ctx *Context, this Value, args []Value) Value {
+		var ptr reflect.Value
+		switch {
+		case ctx.isBindableStruct(field.Type()) && field.CanAddr():
+			ptr = field.Addr()
+		case field.Kind() == reflect.Ptr && !field.IsNil() && ctx.isBindableStruct(field.Type().Elem()):
+			if _, isClass := ctx.classes[field.Type()]; !isClass && !ctx.customEncoding(field.Type()) {
+				ptr = field
+			}
+		}
+		if ptr.IsValid() {
+			return ctx.boundChild(this, name, ptr)
+		}
+		val, err := ctx.Marshal(field.Interface())
+		if err != nil {
+			return ctx.ThrowRangeError("%v", err)
+		}
+		return val
+	})
+}
+
+// boundChild return the bound object of the nested struct cached by the parent object,
+// the cache is a private property of the parent, so that it is released with the parent,
+// the entry of the cache is the pair of the struct pointer and the bound object
+func (ctx *Context) boundChild(parent Value, name string, ptr reflect.Value) Value {
+	if !parent.IsObject() {
+		return ctx.bind(ptr)
+	}
+	if ctx.bindCacheKey == 0 {
+		ctx.bindCacheKey = ctx.privateAtom("quickjs.bound")
+	}
+	cache := ctx.newValue(C.JS_GetProperty(ctx.ref, parent.ref, ctx.bindCacheKey))
+	defer func() { cache.Free() }()
+	if !cache.IsObject() {
+		cache.Free()
+		cache = ctx.newValue(C.JS_NewObjectProto(ctx.ref, C.JS_NewNull()))
+		C.JS_DefinePropertyValue(ctx.ref, parent.ref, ctx.bindCacheKey, cache.Dup().transfer(), 0)
+	}
+
+	key := strconv.FormatUint(uint64(ptr.Pointer()), 16)
+	entry := cache.Get(name)
+	defer entry.Free()
+	if entry.IsArray() && entry.GetStringByUint32(0) == key {
+		return entry.GetByUint32(1)
+	}
+	child := ctx.bind(ptr)
+	pair := ctx.Array()
+	pair.SetByUint32(0, ctx.String(key))
+	pair.SetByUint32(1, child.Dup())
+	cache.Set(name, pair)
+	return child
+}
+
+// isBindableStruct exclude the struct types which are not converted field by field by Context.Marshal,
+// e.g. time.Time, big.Int, and the types with Converter or marshaler, so that Bind and Marshal agree
+func (ctx *Context) isBindableStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !ctx.customEncoding(t)
+}
+
+// bindSetter convert the javascript value to the type of field and assign it
+func (ctx *Context) bindSetter(name string, field reflect.Value) Value {
+	return ctx.NamedFunction("set "+name, 1, func(ctx *Context, this Value, args []Value) Value {
+		jsValue := ctx.Undefined()
+		if len(args) > 0 {
+			jsValue = args[0]
+		}
+		goValue := reflect.New(field.Type())
+		if err := jsValue.Decode(goValue.Interface()); err != nil {
+			return ctx.ThrowTypeError("can not assign to property '%v' of type '%v': %v", name, field.Type(), err)
+		}
+		field.Set(goValue.Elem())
+		return ctx.Undefined()
+	})
+}
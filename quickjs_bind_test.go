@@ -0,0 +1,149 @@
+package quickjs
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"reflect"
+	stdruntime "runtime"
+	"testing"
+	"time"
+)
+
+type BindServerSettings struct {
+	Host string
+	Port int
+}
+
+type BindSettings struct {
+	Name     string
+	Debug    bool
+	Retries  int    `mapstructure:"retries"`
+	Version  string `quickjs:"version,readonly"`
+	Secret   string `quickjs:"-"`
+	Tags     []string
+	Server   BindServerSettings
+	Fallback *BindServerSettings
+	internal int
+}
+
+func (s *BindSettings) Address() string { return s.Server.Host + ":" + s.Name }
+
+func TestContext_Bind(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	settings := &BindSettings{Name: "app", Version: "1.0.0", Secret: "s", Fallback: &BindServerSettings{Port: 80}}
+	obj, err := ctx.Bind(settings)
+	require.NoError(t, err)
+	ctx.Globals().Set("settings", obj)
+
+	v, err := ctx.EvalGlobal(`
+settings.Debug = true;
+settings.retries = 3;
+settings.Tags = ["a", "b"];
+settings.Server.Host = "localhost";
+settings.Server.Port = 8080;
+settings.Fallback.Port = 8081;
+settings.version = "2.0.0";
+[settings.Name, settings.version, typeof settings.Secret, typeof settings.internal, settings.Address(), Object.keys(settings).join()]
+`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{
+		"app", "1.0.0", "undefined", "undefined", "localhost:app",
+		"Name,Debug,retries,version,Tags,Server,Fallback",
+	}, v.InterfaceAndFree())
+
+	assert.True(settings.Debug)
+	assert.Equal(3, settings.Retries)
+	assert.Equal([]string{"a", "b"}, settings.Tags)
+	assert.Equal(BindServerSettings{Host: "localhost", Port: 8080}, settings.Server)
+	assert.Equal(8081, settings.Fallback.Port)
+	assert.Equal("1.0.0", settings.Version)
+
+	// the nested objects are cached until the pointer is changed
+	v, err = ctx.EvalGlobal(`var fallback = settings.Fallback; [settings.Server === settings.Server, fallback === settings.Fallback, Object.getOwnPropertySymbols(settings).length]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{true, true, int64(0)}, v.InterfaceAndFree())
+	settings.Fallback = &BindServerSettings{Port: 82}
+	v, err = ctx.EvalGlobal(`[fallback === settings.Fallback, fallback.Port, settings.Fallback.Port]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{false, int64(8081), int64(82)}, v.InterfaceAndFree())
+
+	// golang side updates are visible in javascript
+	settings.Name = "renamed"
+	settings.Fallback = nil
+	v, err = ctx.EvalGlobal(`[settings.Name, settings.Fallback]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"renamed", nil}, v.InterfaceAndFree())
+
+	// assign the read-only property in strict mode
+	_, err = ctx.EvalGlobal(`"use strict"; settings.version = "2.0.0"`)
+	assert.Error(err)
+
+	v, err = ctx.EvalGlobal(`settings.Server = { Host: "remote", Port: 9090 }`)
+	require.NoError(t, err)
+	v.Free()
+	assert.Equal(BindServerSettings{Host: "remote", Port: 9090}, settings.Server)
+
+	_, err = ctx.Bind(BindSettings{})
+	assert.Error(err)
+	_, err = ctx.Bind((*BindSettings)(nil))
+	assert.Error(err)
+}
+
+type BindVersion struct{ Major, Minor int }
+
+func (v BindVersion) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%d", v.Major, v.Minor)), nil
+}
+
+type BindPoint struct{ X, Y int }
+
+type BindSpecialFields struct {
+	N       *big.Int
+	F       big.Float
+	At      time.Time
+	Version BindVersion
+	Point   *BindPoint
+}
+
+func TestContext_BindSpecialFields(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+	ctx.RegisterConverter(reflect.TypeOf(&BindPoint{}), Converter{ToJS: func(ctx *Context, value interface{}) Value {
+		p := value.(*BindPoint)
+		return ctx.ToJSValue([]int{p.X, p.Y})
+	}})
+
+	// the fields are converted as Context.Marshal does instead of bound as nested structs
+	fields := &BindSpecialFields{N: big.NewInt(7), At: time.Unix(1, 0), Version: BindVersion{1, 2}, Point: &BindPoint{3, 4}}
+	fields.F.SetFloat64(0.5)
+	obj, err := ctx.Bind(fields)
+	require.NoError(t, err)
+	ctx.Globals().Set("fields", obj)
+	marshaled, err := ctx.Marshal(fields)
+	require.NoError(t, err)
+	ctx.Globals().Set("marshaled", marshaled)
+
+	code := `[typeof %[1]s.N, %[1]s.N, typeof %[1]s.F, %[1]s.At instanceof Date, %[1]s.Version, %[1]s.Point]`
+	expected := []interface{}{"bigint", big.NewInt(7), "bigfloat", true, "1.2", []interface{}{int64(3), int64(4)}}
+	for _, name := range []string{"fields", "marshaled"} {
+		v, err := ctx.EvalGlobal(fmt.Sprintf(code, name))
+		require.NoError(t, err)
+		assert.Equal(expected, v.InterfaceAndFree(), name)
+	}
+}
@@ -0,0 +1,168 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// class ids allocated for golang types, the n-th type registered in a runtime takes the n-th id,
+// so that the ids are reused by the runtimes instead of growing the class table of each runtime
+var classLock sync.Mutex
+var classIDPool []C.JSClassID
+
+// goClassID return the class id of the golang type in the runtime
+func (r Runtime) goClassID(t reflect.Type) C.JSClassID {
+	if id, ok := r.state.classIDs[t]; ok {
+		return id
+	}
+	n := len(r.state.classIDs)
+	classLock.Lock()
+	for len(classIDPool) <= n {
+		var id C.JSClassID
+		C.JS_NewClassID(&id)
+		classIDPool = append(classIDPool, id)
+	}
+	id := classIDPool[n]
+	classLock.Unlock()
+	if r.state.classIDs == nil {
+		r.state.classIDs = make(map[reflect.Type]C.JSClassID)
+		r.state.classTypes = make(map[C.JSClassID]reflect.Type)
+	}
+	r.state.classIDs[t] = id
+	r.state.classTypes[id] = t
+	return id
+}
+
+// isGoClassID report whether the class id is registered for a golang type in the runtime
+func (r Runtime) isGoClassID(id C.JSClassID) bool {
+	_, ok := r.state.classTypes[id]
+	return ok
+}
+
+//export goClassFinalizer
+func goClassFinalizer(rt *C.JSRuntime, val C.JSValue) {
+	freeHandle(int64(C.GetOpaqueID(val, C.JS_GetClassID(val))))
+}
+
+// RegisterClass register the golang type as javascript class, and return the class constructor
+//
+// constructor is a golang function which returns a pointer (and an optional error),
+// `new` in javascript will invoke it with the converted arguments, the returned pointer
+// is held by the instance and released by the finalizer of the class,
+// all exported methods of the pointer type (including pointer receiver methods) are defined on the prototype,
+// once registered, the pointers of the type converted by Context.ToJSValue will be instances of the class too,
+// a new instance is created for each conversion, so the instances of the same pointer are not identical (`===`),
+// the class is declared once per runtime, so the contexts of the runtime share the name registered first
+func (ctx *Context) RegisterClass(name string, constructor interface{}) (Value, error) {
+	ctorValue := reflect.ValueOf(constructor)
+	if ctorValue.Kind() != reflect.Func {
+		return ctx.Undefined(), fmt.Errorf("constructor of class '%v' must be a function", name)
+	}
+
+	ctorType := ctorValue.Type()
+	outNum := ctorType.NumOut()
+	if !(outNum == 1 || (outNum == 2 && isErrorType(ctorType.Out(1)))) || ctorType.Out(0).Kind() != reflect.Ptr {
+		return ctx.Undefined(), fmt.Errorf("constructor of class '%v' must return a pointer and an optional error", name)
+	}
+
+	ptrType := ctorType.Out(0)
+	if _, exists := ctx.classes[ptrType]; exists {
+		return ctx.Undefined(), fmt.Errorf("type '%v' has been registered", ptrType)
+	}
+
+	classID := ctx.runtime.goClassID(ptrType)
+	if C.JS_IsRegisteredClass(ctx.runtime.ref, classID) == 0 {
+		namePtr := C.CString(name)
+		defer C.free(unsafe.Pointer(namePtr))
+		if C.NewGoClass(ctx.runtime.ref, classID, namePtr) != 0 {
+			return ctx.Undefined(), fmt.Errorf("register class '%v' failed", name)
+		}
+	}
+
+	proto := ctx.Object()
+	for mIndex := 0; mIndex < ptrType.NumMethod(); mIndex++ {
+		method := ptrType.Method(mIndex)
+		proto.defineProperty(ctx.propertyName(method.Name), ctx.classMethod(classID, method), C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	}
+
+	caller := newReflectCaller(ctorType)
+	ctor := ctx.newFunction(name, caller.requiredArgsNum, funcMagicConstructor, func(ctx *Context, this Value, args []Value) Value {
+		goResult, err := caller.callGo(ctx, ctorValue, this, args)
+		if err != nil {
+			return ctx.throwCallError(err)
+		}
+		if goResult[0].IsNil() {
+			return ctx.ThrowTypeError("constructor of class '%v' returns nil", name)
+		}
+		// `this` inherits from the prototype of new.target, so that sub class works
+		instanceProto := ctx.newValue(C.JS_GetPrototype(ctx.ref, this.ref))
+		defer instanceProto.Free()
+		return ctx.classInstance(classID, instanceProto, goResult[0].Interface())
+	})
+	if ctor.IsException() {
+		proto.Free()
+		return ctor, ctx.Exception()
+	}
+
+	C.JS_SetConstructorBit(ctx.ref, ctor.ref, C.int(1))
+	C.JS_SetConstructor(ctx.ref, ctor.ref, proto.ref)
+	// the class proto takes the ownership of proto
+	C.JS_SetClassProto(ctx.ref, classID, proto.transfer())
+
+	if ctx.classes == nil {
+		ctx.classes = make(map[reflect.Type]C.JSClassID)
+	}
+	ctx.classes[ptrType] = classID
+
+	return ctor, nil
+}
+
+// classMethod create the prototype method, which invokes the method of the golang pointer held by `this`
+func (ctx *Context) classMethod(classID C.JSClassID, method reflect.Method) Value {
+	var in, out []reflect.Type
+	for i := 1; i < method.Type.NumIn(); i++ {
+		in = append(in, method.Type.In(i))
+	}
+	for i := 0; i < method.Type.NumOut(); i++ {
+		out = append(out, method.Type.Out(i))
+	}
+	caller := newReflectCaller(reflect.FuncOf(in, out, method.Type.IsVariadic()))
+
+	return ctx.NamedFunction(ctx.propertyName(method.Name), caller.requiredArgsNum, func(ctx *Context, this Value, args []Value) Value {
+		receiver, ok := this.goClassInstance()
+		if !ok || C.JS_GetClassID(this.ref) != classID {
+			return ctx.ThrowTypeError("method '%v' must be invoked on the class instance", method.Name)
+		}
+		return caller.call(ctx, reflect.ValueOf(receiver).Method(method.Index), this, args)
+	})
+}
+
+// classInstance create an instance of class with the golang pointer
+func (ctx *Context) classInstance(classID C.JSClassID, proto Value, goValue interface{}) Value {
+	val := ctx.newValue(C.JS_NewObjectProtoClass(ctx.ref, proto.ref, classID))
+	if val.IsException() {
+		return val
+	}
+	C.SetOpaqueID(val.ref, C.int64_t(storeHandle(goValue)))
+	return val
+}
+
+// goClassInstance return the golang pointer held by the instance of class registered by Context.RegisterClass
+func (v Value) goClassInstance() (interface{}, bool) {
+	classID := C.JS_GetClassID(v.ref)
+	if classID == 0 || !v.ctx.runtime.isGoClassID(classID) {
+		return nil, false
+	}
+	return restoreHandle(int64(C.GetOpaqueID(v.ref, classID)))
+}
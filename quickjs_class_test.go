@@ -0,0 +1,192 @@
+package quickjs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	stdruntime "runtime"
+	"testing"
+)
+
+type ClassCounter struct {
+	Name  string
+	count int
+}
+
+func NewClassCounter(name string, start *int) (*ClassCounter, error) {
+	if len(name) == 0 {
+		return nil, errors.New("name is required")
+	}
+	c := &ClassCounter{Name: name}
+	if start != nil {
+		c.count = *start
+	}
+	return c, nil
+}
+
+func (c *ClassCounter) Add(n int) int {
+	c.count += n
+	return c.count
+}
+
+func (c *ClassCounter) Clone() *ClassCounter {
+	return &ClassCounter{Name: c.Name + "-clone", count: c.count}
+}
+
+func (c ClassCounter) Label() string { return c.Name }
+
+func handleStoreLen() int {
+	handleLock.Lock()
+	defer handleLock.Unlock()
+	return len(handleStore)
+}
+
+func TestContext_RegisterClass(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	Counter, err := ctx.RegisterClass("Counter", NewClassCounter)
+	require.NoError(t, err)
+	ctx.Globals().Set("Counter", Counter)
+
+	_, err = ctx.RegisterClass("Counter", NewClassCounter)
+	assert.Error(err)
+
+	v, err := ctx.EvalGlobal(`
+class NamedCounter extends Counter {
+	describe() { return this.Label() + ":" + this.Add(0) }
+}
+var c = new Counter("a", 1);
+c.Add(2);
+const clone = c.Clone();
+const named = new NamedCounter("b");
+named.Add(5);
+[c.Add(3), c instanceof Counter, clone instanceof Counter, clone.Label(), named.describe(), named instanceof Counter, Counter.name, Counter.length]
+`)
+	require.NoError(t, err)
+	defer v.Free()
+	assert.Equal([]interface{}{int64(6), true, true, "a-clone", "b:5", true, "Counter", int64(1)}, v.Interface())
+
+	c := ctx.Globals().Get("c")
+	defer c.Free()
+	counter, ok := c.Interface().(*ClassCounter)
+	require.True(t, ok)
+	assert.Equal(6, counter.count)
+	counter.Add(4)
+
+	result, err := ctx.EvalGlobal(`c.Add(0)`)
+	require.NoError(t, err)
+	assert.Equal(int64(10), result.Int64())
+
+	ctx.Globals().SetGoValue("wrapped", &ClassCounter{Name: "go"})
+	result, err = ctx.EvalGlobal(`wrapped instanceof Counter && wrapped.Label()`)
+	require.NoError(t, err)
+	defer result.Free()
+	assert.Equal("go", result.String())
+
+	ctx.Globals().SetGoValue("countOf", func(c *ClassCounter) int { return c.count })
+	result, err = ctx.EvalGlobal(`countOf(c)`)
+	require.NoError(t, err)
+	assert.Equal(int64(10), result.Int64())
+
+	_, err = ctx.EvalGlobal(`new Counter("")`)
+	assert.Error(err)
+	assert.Equal("Error: name is required", err.Error())
+
+	_, err = ctx.EvalGlobal(`Counter.prototype.Add.call({}, 1)`)
+	assert.Error(err)
+
+	_, err = ctx.EvalGlobal(`Counter("a")`)
+	assert.Error(err)
+}
+
+func TestContext_RegisterClassFinalizer(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	Counter, err := ctx.RegisterClass("Counter", NewClassCounter)
+	require.NoError(t, err)
+	ctx.Globals().Set("Counter", Counter)
+
+	before := handleStoreLen()
+	result, err := ctx.EvalGlobal(`for (let i = 0; i < 100; i++) { new Counter("a") }`)
+	require.NoError(t, err)
+	result.Free()
+	r.RunGC()
+	assert.Equal(before, handleStoreLen())
+
+	_, err = ctx.RegisterClass("Invalid", func() ClassCounter { return ClassCounter{} })
+	assert.Error(err)
+}
+
+type ClassTally struct{ total int }
+
+func NewClassTally() *ClassTally { return &ClassTally{} }
+
+func (t *ClassTally) Add(n int) int {
+	t.total += n
+	return t.total
+}
+
+func TestRuntime_ClassIDs(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	// instanceClass return the class name of the instance created by the code
+	instanceClass := func(r Runtime, ctx *Context, code string) string {
+		v, err := ctx.EvalGlobal(code)
+		require.NoError(t, err)
+		defer v.Free()
+		for _, object := range r.LeakReport().Objects {
+			if object.Class != "Object" && object.Type == "object" {
+				return object.Class
+			}
+		}
+		return ""
+	}
+
+	r1 := NewRuntime()
+	defer r1.Free()
+	ctx1 := r1.NewContext()
+	defer ctx1.Free()
+	Counter, err := ctx1.RegisterClass("Counter", NewClassCounter)
+	require.NoError(t, err)
+	ctx1.Globals().Set("Counter", Counter)
+
+	// the types are registered with their own names and ids in each runtime
+	r2 := NewRuntime()
+	defer r2.Free()
+	ctx2 := r2.NewContext()
+	defer ctx2.Free()
+	Tally, err := ctx2.RegisterClass("Tally", NewClassTally)
+	require.NoError(t, err)
+	ctx2.Globals().Set("Tally", Tally)
+	Counter, err = ctx2.RegisterClass("OtherCounter", NewClassCounter)
+	require.NoError(t, err)
+	ctx2.Globals().Set("Counter", Counter)
+
+	assert.Equal("Counter", instanceClass(r1, ctx1, `new Counter("a")`))
+	assert.Equal("OtherCounter", instanceClass(r2, ctx2, `new Counter("a")`))
+	assert.Equal("Tally", instanceClass(r2, ctx2, `new Tally()`))
+	assert.Equal(r1.state.classIDs[reflect.TypeOf(&ClassCounter{})], r2.state.classIDs[reflect.TypeOf(&ClassTally{})])
+
+	result, err := ctx2.EvalGlobal(`const tally = new Tally(); tally.Add(2); [tally.Add(3), new Counter("b").Add(1)]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{int64(5), int64(1)}, result.InterfaceAndFree())
+	_, err = ctx2.EvalGlobal(`Counter.prototype.Add.call(tally, 1)`)
+	assert.Error(err)
+}
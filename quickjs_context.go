@@ -19,10 +19,23 @@ import (
 type Context struct {
 	ref               *C.JSContext
 	globals           *Value
-	proxy             *Value
 	runtime           *Runtime
 	typescriptSupport bool
 	typescriptOption  *GoJSObject
+	funcPtrs          map[int64]struct{}
+	classes           map[reflect.Type]C.JSClassID
+	namingPolicy      NamingPolicy
+	goMapAsJSMap      bool
+	conversionLimits  ConversionLimits
+	converters        map[reflect.Type]Converter
+	integerPolicy     IntegerPolicy
+	commonJS          *commonJS
+	// bindCacheKey is the private symbol atom of the bound nested structs cached by Context.Bind
+	bindCacheKey C.JSAtom
+	// intrinsicsKey is the private symbol atom of the builtin functions captured when the context is created
+	intrinsicsKey C.JSAtom
+	// references of the javascript values held by golang values, e.g. the exported functions
+	references referenceStore
 }
 
 func (ctx *Context) WithTypeScript(version string) error {
@@ -42,38 +55,89 @@ func (ctx *Context) WithTypeScript(version string) error {
 	return nil
 }
 
+// SetNamingPolicy set the naming policy for the methods and untagged fields of golang structs converted to javascript,
+// the golang names are kept by default
+func (ctx *Context) SetNamingPolicy(policy NamingPolicy) { ctx.namingPolicy = policy }
+
+// propertyName convert the golang name to property name with the naming policy
+func (ctx *Context) propertyName(name string) string {
+	if ctx.namingPolicy == nil {
+		return name
+	}
+	return ctx.namingPolicy(name)
+}
+
 func (ctx *Context) Free() {
 
-	if ctx.proxy != nil {
-		ctx.proxy.Free()
+	if ctx.commonJS != nil {
+		ctx.commonJS.free()
 	}
 
+	ctx.freeReferences()
+
 	if ctx.globals != nil {
 		ctx.globals.Free()
 	}
 
+	if ctx.bindCacheKey != 0 {
+		C.JS_FreeAtom(ctx.ref, ctx.bindCacheKey)
+	}
+	C.JS_FreeAtom(ctx.ref, ctx.intrinsicsKey)
+
+	if leaks := ctx.leaks(); leaks != nil {
+		leaks.untrack(unsafe.Pointer(ctx.ref))
+	}
+	delete(ctx.runtime.state.contexts, ctx.ref)
 	C.JS_FreeContext(ctx.ref)
+
+	freeContextFuncPtrs(ctx)
 }
 
-func (ctx *Context) Function(fn JSFunction) Value {
-	val := ctx.eval(`(proxy, id) => function() { return proxy.call(this, id, ...arguments); }`)
-	if val.IsException() {
-		return val
-	}
-	defer val.Free()
+// Function create a native function without name
+func (ctx *Context) Function(fn JSFunction) Value { return ctx.NamedFunction("", 0, fn) }
 
-	funcPtr := storeFuncPtr(funcEntry{ctx: ctx, fn: fn})
-	funcPtrVal := ctx.Int64(funcPtr)
+// NamedFunction create a native function with the `name` and `length` (arity) properties
+func (ctx *Context) NamedFunction(name string, length int, fn JSFunction) Value {
+	return ctx.newFunction(name, length, funcMagicFunction, fn)
+}
 
-	if ctx.proxy == nil {
-		ctx.proxy = &Value{
-			ctx: ctx,
-			ref: C.JS_NewCFunction(ctx.ref, (*C.JSCFunction)(unsafe.Pointer(C.InvokeProxy)), nil, C.int(0)),
-		}
+// Constructor create a native function which could be invoked with `new`,
+// fn will receive a new object (inherit from the `prototype` of new.target) as `this`,
+// and the object will be the result of `new` unless fn returns another object
+func (ctx *Context) Constructor(name string, length int, fn JSFunction) Value {
+	ctor := ctx.newFunction(name, length, funcMagicConstructor, fn)
+	if ctor.IsException() {
+		return ctor
 	}
+	C.JS_SetConstructorBit(ctx.ref, ctor.ref, C.int(1))
+	proto := ctx.Object()
+	defer proto.Free()
+	C.JS_SetConstructor(ctx.ref, ctor.ref, proto.ref)
+	return ctor
+}
 
-	args := []C.JSValue{ctx.proxy.ref, funcPtrVal.ref}
-	return ctx.newValue(C.JS_Call(ctx.ref, val.ref, ctx.Null().ref, C.int(len(args)), &args[0]))
+func (ctx *Context) newFunction(name string, length int, magic int, fn JSFunction) Value {
+	// the handle object releases the function entry once it is finalized
+	funcPtr := storeFuncPtr(funcEntry{ctx: ctx, fn: fn})
+	funcPtrVal := ctx.newValue(C.JS_NewObjectClass(ctx.ref, C.int(funcPtrClassID)))
+	defer funcPtrVal.Free()
+	C.SetOpaqueID(funcPtrVal.ref, C.int64_t(funcPtr))
+
+	val := ctx.newValue(C.JS_NewCFunctionData(
+		ctx.ref,
+		(*C.JSCFunctionData)(unsafe.Pointer(C.InvokeProxy)),
+		C.int(length),
+		C.int(magic),
+		C.int(1),
+		&funcPtrVal.ref,
+	))
+	if val.IsException() {
+		return val
+	}
+	if len(name) > 0 {
+		val.defineProperty("name", ctx.String(name), C.JS_PROP_CONFIGURABLE)
+	}
+	return val
 }
 
 func (ctx *Context) Null() Value {
@@ -88,12 +152,35 @@ func (ctx *Context) Uninitialized() Value {
 	return Value{ctx: ctx, ref: C.JS_NewUninitialized()}
 }
 
+// Error create a new Error object with the stack of current native function call,
+// the err will be kept in the object, and could be unwrapped from the *Error converted back
 func (ctx *Context) Error(err error) Value {
-	val := Value{ctx: ctx, ref: C.JS_NewError(ctx.ref)}
-	val.Set("message", ctx.String(err.Error()))
+	val := ctx.newValue(C.JS_NewError(ctx.ref))
+	if val.IsException() {
+		return val
+	}
+	val.defineProperty("message", ctx.String(err.Error()), C.JS_PROP_WRITABLE|C.JS_PROP_CONFIGURABLE)
+	C.JS_SetErrorBacktrace(ctx.ref, val.ref)
+	val.defineProperty("goError", ctx.goValue(err), C.JS_PROP_CONFIGURABLE)
 	return val
 }
 
+// throwPanic convert a recovered golang panic to javascript InternalError,
+// the golang stack will be set as the `goStack` property
+func (ctx *Context) throwPanic(r interface{}, stack []byte) Value {
+	message := fmt.Sprintf("panic: %v", r)
+	ctx.ThrowInternalError("%s", message)
+	val := ctx.newValue(C.JS_GetException(ctx.ref))
+	if !val.IsObject() {
+		return ctx.Throw(val)
+	}
+	// the message thrown by quickjs is truncated
+	val.defineProperty("message", ctx.String(message), C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	val.defineProperty("goStack", ctx.String(string(stack)), C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	val.defineProperty("goPanic", ctx.goValue(r), C.JS_PROP_CONFIGURABLE)
+	return ctx.Throw(val)
+}
+
 func (ctx *Context) Bool(b bool) Value {
 	bv := 0
 	if b {
@@ -130,6 +217,7 @@ func (ctx *Context) String(v string) Value {
 
 func (ctx *Context) newValue(ref C.JSValue) Value {
 	rt := Value{ctx: ctx, ref: ref}
+	rt.track(2)
 	return rt
 }
 
@@ -143,6 +231,11 @@ func (ctx *Context) Atom(v string) Atom {
 func (ctx *Context) eval(code string) Value { return ctx.evalFile(code, "code", 0) }
 
 func (ctx *Context) evalFile(code, filename string, mod int) Value {
+	if mod&C.JS_EVAL_TYPE_MODULE != 0 && mod&C.JS_EVAL_FLAG_COMPILE_ONLY == 0 {
+		_, result := ctx.evalModule(code, filename, mod)
+		return result
+	}
+
 	var realCode string
 
 	if ctx.typescriptSupport {
@@ -161,7 +254,7 @@ func (ctx *Context) evalFile(code, filename string, mod int) Value {
 	filenamePtr := C.CString(filename)
 	defer C.free(unsafe.Pointer(filenamePtr))
 
-	return Value{ctx: ctx, ref: C.JS_Eval(ctx.ref, codePtr, C.size_t(len(code)), filenamePtr, C.int(mod))}
+	return ctx.newValue(C.JS_Eval(ctx.ref, codePtr, C.size_t(len(code)), filenamePtr, C.int(mod)))
 }
 
 func (ctx *Context) CompileTypeScript(code string) (string, error) {
@@ -210,17 +303,15 @@ func (ctx *Context) EvalFile(code, filename string, mod int) (Value, error) {
 
 func (ctx *Context) Globals() Value {
 	if ctx.globals == nil {
-		ctx.globals = &Value{
-			ctx:    ctx,
-			ref:    C.JS_GetGlobalObject(ctx.ref),
-			global: true,
-		}
+		globals := ctx.newValue(C.JS_GetGlobalObject(ctx.ref))
+		globals.global = true
+		ctx.globals = &globals
 	}
 	return *ctx.globals
 }
 
 func (ctx *Context) Throw(v Value) Value {
-	return Value{ctx: ctx, ref: C.JS_Throw(ctx.ref, v.ref)}
+	return ctx.newValue(C.JS_Throw(ctx.ref, v.transfer()))
 }
 
 func (ctx *Context) ThrowError(err error) Value { return ctx.Throw(ctx.Error(err)) }
@@ -261,7 +352,7 @@ func (ctx *Context) ThrowInternalError(format string, args ...interface{}) Value
 }
 
 func (ctx *Context) Exception() error {
-	val := Value{ctx: ctx, ref: C.JS_GetException(ctx.ref)}
+	val := ctx.newValue(C.JS_GetException(ctx.ref))
 	defer val.Free()
 	return val.Error()
 }
@@ -271,105 +362,180 @@ func (ctx *Context) Object() Value {
 	return ctx.newValue(C.JS_NewObject(ctx.ref))
 }
 
-// ToJSValue convert golang object to quickjs.Value
+// ToJSValue convert golang object to quickjs.Value,
+// undefined will be returned if the conversion exceeds the limits, use Context.Marshal to get the error
 func (ctx *Context) ToJSValue(value interface{}) Value {
+	val, _ := ctx.Marshal(value)
+	return val
+}
 
-	if value == nil {
-		return ctx.Undefined()
+// This is the `this` of javascript function call,
+// golang function could receive it as the leading parameter (after the optional *Context parameter)
+type This struct{ Value }
+
+// reflectFunction wrap a golang function as native function
+func (ctx *Context) reflectFunction(name string, reflectValue reflect.Value) Value {
+	caller := newReflectCaller(reflectValue.Type())
+	return ctx.NamedFunction(name, caller.requiredArgsNum, func(ctx *Context, this Value, jsArgs []Value) Value {
+		return caller.call(ctx, reflectValue, this, jsArgs)
+	})
+}
+
+// argumentTypeError is thrown as TypeError
+type argumentTypeError struct{ error }
+
+func (ctx *Context) throwCallError(err error) Value {
+	if _, ok := err.(argumentTypeError); ok {
+		return ctx.ThrowTypeError("%v", err)
+	}
+	return ctx.ThrowError(err)
+}
+
+// reflectCaller invoke golang function with javascript arguments
+//
+// the leading *Context and This parameters will be filled with the current call,
+// the trailing pointer, interface and quickjs.Value parameters are optional,
+// and the remaining javascript arguments will be passed as the variadic parameter
+type reflectCaller struct {
+	reflectType     reflect.Type
+	withContext     bool
+	withThis        bool
+	leadingArgsNum  int
+	fixedArgsNum    int
+	requiredArgsNum int
+}
+
+func newReflectCaller(reflectType reflect.Type) *reflectCaller {
+	c := &reflectCaller{reflectType: reflectType}
+	funcArgsNum := reflectType.NumIn()
+
+	c.withContext = c.leadingArgsNum < funcArgsNum && reflectType.In(c.leadingArgsNum) == contextType
+	if c.withContext {
+		c.leadingArgsNum++
+	}
+	c.withThis = c.leadingArgsNum < funcArgsNum && reflectType.In(c.leadingArgsNum) == thisType
+	if c.withThis {
+		c.leadingArgsNum++
+	}
+
+	c.fixedArgsNum = funcArgsNum - c.leadingArgsNum
+	if reflectType.IsVariadic() {
+		c.fixedArgsNum--
+	}
+	c.requiredArgsNum = c.fixedArgsNum
+	for c.requiredArgsNum > 0 && isOptionalType(reflectType.In(c.leadingArgsNum+c.requiredArgsNum-1)) {
+		c.requiredArgsNum--
 	}
+	return c
+}
 
-	reflectValue := reflect.ValueOf(value)
-	reflectType := reflectValue.Type()
-
-	// if it is quickjs.Value, return it directly
-	if reflectType == reflect.TypeOf(Value{}) {
-		return value.(Value)
-	}
-	// if is reflect.Value, unwrap the real value
-	if reflectType == reflect.TypeOf(reflect.Value{}) {
-		value := value.(reflect.Value).Interface()
-		return ctx.ToJSValue(value)
-	}
-
-	switch reflectValue.Kind() {
-	case reflect.String:
-		return ctx.String(reflectValue.String())
-	case reflect.Int8, reflect.Int, reflect.Int16, reflect.Int32:
-		return ctx.Int32(int32(reflectValue.Int()))
-	case reflect.Int64:
-		return ctx.Int64(reflectValue.Int())
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
-		return ctx.Uint32(uint32(reflectValue.Uint()))
-	case reflect.Uint64:
-		return ctx.BigUint64(reflectValue.Uint())
-	case reflect.Float32, reflect.Float64:
-		return ctx.Float64(reflectValue.Float())
-	case reflect.Bool:
-		return ctx.Bool(reflectValue.Bool())
-	case reflect.Map:
-		obj := ctx.Object()
-		for _, key := range reflectValue.MapKeys() {
-			innerValue := reflectValue.MapIndex(key)
-			obj.Set(key.String(), ctx.ToJSValue(innerValue.Interface()))
+func (c *reflectCaller) toGoArg(index int, jsArg Value, argType reflect.Type) (reflect.Value, error) {
+	if argType.Kind() == reflect.Interface {
+		goValue, err := jsArg.Export()
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("argument %v: %w", index, err)
 		}
-		return obj
-	case reflect.Struct:
-		obj := ctx.Object()
-		for fIndex := 0; fIndex < reflectValue.NumField(); fIndex++ {
-			field := reflectValue.Field(fIndex)
-			fieldName := reflectType.Field(fIndex).Name
-			if isExportedName(fieldName) {
-				obj.Set(fieldName, ctx.ToJSValue(field.Interface()))
-			}
+		if goValue == nil {
+			return reflect.Zero(argType), nil
 		}
-		methodCount := reflectType.NumMethod()
-		for mIndex := 0; mIndex < methodCount; mIndex++ {
-			method := reflectValue.Method(mIndex)
-			methodName := reflectType.Method(mIndex).Name
-			if isExportedName(methodName) {
-				obj.Set(methodName, ctx.ToJSValue(method.Interface()))
-			}
+		if !reflect.TypeOf(goValue).AssignableTo(argType) {
+			return reflect.Value{}, argumentTypeError{fmt.Errorf("argument %v: can not use '%T' as '%v'", index, goValue, argType)}
+		}
+		return reflect.ValueOf(goValue), nil
+	}
+	goArg, err := jsArg.ToReflectValue(argType)
+	if err != nil {
+		return reflect.Value{}, argumentTypeError{fmt.Errorf("argument %v: %w", index, err)}
+	}
+	if !goArg.IsValid() {
+		return reflect.Zero(argType), nil
+	}
+	if !goArg.Type().AssignableTo(argType) {
+		return goArg, argumentTypeError{fmt.Errorf("argument %v: can not use '%v' as '%v'", index, goArg.Type(), argType)}
+	}
+	return goArg, nil
+}
+
+// toGoArgs convert the javascript arguments to golang function arguments
+func (c *reflectCaller) toGoArgs(ctx *Context, this Value, jsArgs []Value) ([]reflect.Value, error) {
+	if len(jsArgs) < c.requiredArgsNum {
+		return nil, fmt.Errorf("arguments is not enough, the function require '%v' parameters", c.requiredArgsNum)
+	}
+
+	funcArgsNum := c.reflectType.NumIn()
+	goFuncArgs := make([]reflect.Value, 0, funcArgsNum)
+
+	if c.withContext {
+		goFuncArgs = append(goFuncArgs, reflect.ValueOf(ctx))
+	}
+	if c.withThis {
+		goFuncArgs = append(goFuncArgs, reflect.ValueOf(This{this}))
+	}
+
+	for i := 0; i < c.fixedArgsNum; i++ {
+		argType := c.reflectType.In(c.leadingArgsNum + i)
+		jsArg := ctx.Undefined()
+		if i < len(jsArgs) {
+			jsArg = jsArgs[i]
 		}
-		return obj
-	case reflect.Slice:
-		obj := ctx.Array()
-		for arrayItemIndex := 0; arrayItemIndex < reflectValue.Len(); arrayItemIndex++ {
-			arrayItem := reflectValue.Index(arrayItemIndex)
-			arrayItemValue := ctx.ToJSValue(arrayItem.Interface())
-			obj.SetByInt64(int64(arrayItemIndex), arrayItemValue)
+		goArg, err := c.toGoArg(i, jsArg, argType)
+		if err != nil {
+			return nil, err
 		}
-		return obj
-	case reflect.Func:
-		funcArgsNum := reflectType.NumIn()
-		return ctx.Function(func(ctx *Context, this Value, jsArgs []Value) Value {
-			if len(jsArgs) < funcArgsNum {
-				return ctx.ThrowError(fmt.Errorf("arguments is not enough, the function require '%v' parameters", funcArgsNum))
+		goFuncArgs = append(goFuncArgs, goArg)
+	}
+
+	if c.reflectType.IsVariadic() {
+		argType := c.reflectType.In(funcArgsNum - 1).Elem()
+		for i := c.fixedArgsNum; i < len(jsArgs); i++ {
+			goArg, err := c.toGoArg(i, jsArgs[i], argType)
+			if err != nil {
+				return nil, err
 			}
+			goFuncArgs = append(goFuncArgs, goArg)
+		}
+	}
 
-			var goFuncArgs []reflect.Value
+	return goFuncArgs, nil
+}
 
-			for i := 0; i < funcArgsNum; i++ {
-				argType := reflectType.In(i)
-				jsArg := jsArgs[i]
-				goFuncArgs = append(goFuncArgs, jsArg.ToReflectValue(argType))
-			}
+// callGo invoke the golang function, the trailing error result will be returned separately
+func (c *reflectCaller) callGo(ctx *Context, fn reflect.Value, this Value, jsArgs []Value) ([]reflect.Value, error) {
+	goFuncArgs, err := c.toGoArgs(ctx, this, jsArgs)
+	if err != nil {
+		return nil, err
+	}
 
-			goFuncResult := reflectValue.Call(goFuncArgs)
+	goFuncResult := fn.Call(goFuncArgs)
 
-			if len(goFuncResult) == 0 {
-				return ctx.Undefined()
-			} else if len(goFuncResult) == 1 {
-				return ctx.ToJSValue(goFuncResult[0])
-			} else {
-				return ctx.ToJSValue(goFuncResult)
-			}
+	if resultNum := len(goFuncResult); resultNum > 0 && isErrorType(c.reflectType.Out(resultNum-1)) {
+		if errResult := goFuncResult[resultNum-1]; !isNilError(errResult) {
+			return nil, errResult.Interface().(error)
+		}
+		goFuncResult = goFuncResult[:resultNum-1]
+	}
 
-			return ctx.ThrowError(fmt.Errorf("not support call golang function directly"))
-		})
-	default:
-		// ignore
+	return goFuncResult, nil
+}
+
+func (c *reflectCaller) call(ctx *Context, fn reflect.Value, this Value, jsArgs []Value) Value {
+	goFuncResult, err := c.callGo(ctx, fn, this, jsArgs)
+	if err != nil {
+		return ctx.throwCallError(err)
+	}
+
+	var result Value
+	if len(goFuncResult) == 0 {
+		return ctx.Undefined()
+	} else if len(goFuncResult) == 1 {
+		result, err = ctx.Marshal(goFuncResult[0])
+	} else {
+		result, err = ctx.Marshal(goFuncResult)
+	}
+	if err != nil {
+		return ctx.ThrowRangeError("%v", err)
 	}
-	return ctx.Undefined()
+	return result
 }
 
 // ParseJson parse Value from JSON string
@@ -392,13 +558,19 @@ func (ctx *Context) NewPromise(runner PromiseRunner) Value {
 	return ctx.Globals().Get("Promise").New(cb)
 }
 
+// ExecutePendingJob execute a pending job of the runtime, e.g. the promise reactions and the dynamic imports,
+// io.EOF is returned if there is no job
 func (ctx *Context) ExecutePendingJob() error {
-
-	code := C.JS_ExecutePendingJob(ctx.runtime.ref, &ctx.ref)
+	// the job may belong to another context of the runtime, it is NULL if there is no job
+	var jobCtx *C.JSContext
+	code := C.JS_ExecutePendingJob(ctx.runtime.ref, &jobCtx)
 	if code <= 0 {
 		if code == 0 {
 			return io.EOF
 		}
+		if owner := restoreContext(jobCtx); owner != nil {
+			return owner.Exception()
+		}
 		return ctx.Exception()
 	}
 
@@ -408,5 +580,5 @@ func (ctx *Context) ExecutePendingJob() error {
 type PromiseRunner = func(resolve, reject Value)
 
 func (ctx *Context) Array() Value {
-	return Value{ctx: ctx, ref: C.JS_NewArray(ctx.ref)}
+	return ctx.newValue(C.JS_NewArray(ctx.ref))
 }
@@ -1,6 +1,8 @@
 package quickjs
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	stdruntime "runtime"
 	"strings"
@@ -169,3 +171,209 @@ func TestContext_WithGoRoutineCall(t *testing.T) {
 	}
 
 }
+
+var errDemoNotFound = errors.New("not found")
+
+type demoError struct{ code int }
+
+func (e *demoError) Error() string { return "demo error" }
+
+func TestContext_ToJSValueWithErrorResult(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+
+	assert := assert.New(t)
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	Global := ctx.Globals()
+	Global.SetGoValue("find", func(key string) (string, error) {
+		if key == "a" {
+			return "found a", nil
+		}
+		return "", errDemoNotFound
+	})
+	Global.SetGoValue("check", func(code int) *demoError {
+		if code == 0 {
+			return nil
+		}
+		return &demoError{code}
+	})
+	Global.SetGoValue("pair", func() (int, string, error) { return 1, "2", nil })
+	Global.SetGoValue("wrapped", func() (string, error) {
+		var err *demoError
+		return "typed nil", err
+	})
+
+	v, err := ctx.EvalGlobal(`
+let message;
+try { find("b") } catch (e) { message = e.message }
+[find("a"), message, check(0), pair(), wrapped()]
+`)
+	assert.Nil(err)
+	defer v.Free()
+	assert.Equal([]interface{}{"found a", "not found", nil, []interface{}{int64(1), "2"}, "typed nil"}, v.Interface())
+
+	_, err = ctx.EvalGlobal(`find("b")`)
+	assert.True(errors.Is(err, errDemoNotFound))
+	assert.Equal("Error: not found", err.Error())
+
+	_, err = ctx.EvalGlobal(`check(42)`)
+	var demoErr *demoError
+	assert.True(errors.As(err, &demoErr))
+	assert.Equal(42, demoErr.code)
+
+	// the errors are not created by the global Error overwritten by script
+	v, err = ctx.EvalGlobal(`
+globalThis.Error = function() { return { hijacked: true } };
+let caught;
+try { find("b") } catch (e) { caught = e }
+[caught.hijacked, caught.message, caught.stack.includes("<eval>")]
+`)
+	assert.Nil(err)
+	assert.Equal([]interface{}{nil, "not found", true}, v.InterfaceAndFree())
+}
+
+type DemoOption struct {
+	Prefix string `mapstructure:"prefix"`
+}
+
+func TestContext_ToJSValueWithFuncParameters(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+
+	assert := assert.New(t)
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	Global := ctx.Globals()
+	Global.SetGoValue("sprintf", fmt.Sprintf)
+	Global.SetGoValue("sum", func(values ...int) int {
+		total := 0
+		for _, v := range values {
+			total += v
+		}
+		return total
+	})
+	Global.SetGoValue("greet", func(name string, option *DemoOption) string {
+		if option == nil {
+			return "hello " + name
+		}
+		return option.Prefix + " " + name
+	})
+	Global.SetGoValue("kind", func(v interface{}) string { return fmt.Sprintf("%T", v) })
+	Global.SetGoValue("typeOf", func(v Value) string { return v.TypeOf() })
+	Global.SetGoValue("method", func(ctx *Context, this This, suffix string) Value {
+		return ctx.String(this.GetString("name") + suffix)
+	})
+
+	v, err := ctx.EvalGlobal(`
+const obj = { name: "obj", method };
+[
+	sprintf("%s-%d", "a", 1), sprintf("plain"), sum(), sum(1, 2, 3),
+	greet("js"), greet("js", { prefix: "hi" }), greet.length,
+	kind(1), kind("s"), kind(), typeOf(() => 1), typeOf(),
+	obj.method("!"), method.length
+]
+`)
+	assert.Nil(err)
+	defer v.Free()
+	assert.Equal([]interface{}{
+		"a-1", "plain", int64(0), int64(6),
+		"hello js", "hi js", int64(1),
+		"int64", "string", "<nil>", "function", "undefined",
+		"obj!", int64(1),
+	}, v.Interface())
+
+	_, err = ctx.EvalGlobal(`greet()`)
+	assert.Error(err)
+
+	_, err = ctx.EvalGlobal(`kind.call(null)`)
+	assert.Nil(err)
+
+	Global.SetGoValue("stringify", func(s fmt.Stringer) string { return s.String() })
+	_, err = ctx.EvalGlobal(`stringify({})`)
+	assert.Error(err)
+	assert.Contains(err.Error(), "TypeError: argument 0: can not use 'map[string]interface {}' as 'fmt.Stringer'")
+}
+
+type DemoTaggedBase struct {
+	ID      int    `json:"id"`
+	Comment string `json:"comment"`
+}
+
+type demoTaggedAudit struct {
+	CreatedBy string
+}
+
+type DemoTagged struct {
+	DemoTaggedBase
+	*demoTaggedAudit
+	Comment  string `mapstructure:"note"`
+	Url      string `mapstructure:"url"`
+	Password string `json:"-"`
+	Optional string `json:"optional,omitempty"`
+	UserName string
+	Labels   []string `json:",omitempty"`
+}
+
+func (d DemoTagged) DisplayName() string { return d.UserName }
+
+func TestContext_ToJSValueWithStructTags(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+
+	assert := assert.New(t)
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	demo := DemoTagged{
+		DemoTaggedBase:  DemoTaggedBase{ID: 1, Comment: "base"},
+		demoTaggedAudit: &demoTaggedAudit{CreatedBy: "admin"},
+		Comment:         "c",
+		Url:             "http://localhost",
+		Password:        "secret",
+		UserName:        "theo",
+	}
+
+	v := ctx.ToJSValue(demo)
+	names, err := v.PropertyNames()
+	assert.NoError(err)
+	var keys []string
+	for _, name := range names {
+		keys = append(keys, name.String())
+	}
+	assert.Equal([]string{"note", "url", "UserName", "id", "comment", "CreatedBy", "DisplayName"}, keys)
+	assert.Equal("c", v.GetString("note"))
+	assert.Equal(int64(1), v.GetInt64("id"))
+	assert.Equal("base", v.GetString("comment"))
+	assert.Equal("admin", v.GetString("CreatedBy"))
+	v.Free()
+
+	// round trip with Value.Decode
+	v = ctx.ToJSValue(demo)
+	decoded := &DemoTagged{}
+	assert.NoError(v.Decode(decoded))
+	assert.Equal("http://localhost", decoded.Url)
+	assert.Equal("c", decoded.Comment)
+	v.Free()
+
+	ctx.SetNamingPolicy(LowerCamelCase)
+	demo.demoTaggedAudit = nil
+	demo.Optional = "o"
+	v = ctx.ToJSValue(demo)
+	defer v.Free()
+	assert.False(v.HasProperty("createdBy"))
+	assert.Equal("o", v.GetString("optional"))
+	assert.Equal("theo", v.GetString("userName"))
+	displayName := v.Get("displayName")
+	defer displayName.Free()
+	assert.True(displayName.IsFunction())
+	assert.Equal("displayName", displayName.GetString("name"))
+}
@@ -0,0 +1,457 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	stdruntime "runtime"
+	"time"
+	"unsafe"
+)
+
+// ConversionLimits of the conversion between golang and javascript values, zero means unlimited
+type ConversionLimits struct {
+	// MaxDepth is the max nesting depth of structs, maps, slices, objects and arrays
+	MaxDepth int
+	// MaxElements is the max number of values converted in one conversion
+	MaxElements int
+}
+
+// ErrMaxDepthExceeded is returned when the value is nested deeper than ConversionLimits.MaxDepth
+var ErrMaxDepthExceeded = errors.New("conversion exceeds the max depth")
+
+// ErrMaxElementsExceeded is returned when the value contains more than ConversionLimits.MaxElements values
+var ErrMaxElementsExceeded = errors.New("conversion exceeds the max elements")
+
+// SetConversionLimits set the limits of Context.ToJSValue and Value.Interface,
+// the conversions are cycle-safe without limits, the limits protect from the huge values
+func (ctx *Context) SetConversionLimits(limits ConversionLimits) { ctx.conversionLimits = limits }
+
+// conversionState track the depth and elements of one conversion
+type conversionState struct {
+	limits   ConversionLimits
+	depth    int
+	elements int
+}
+
+func (s *conversionState) count() error {
+	s.elements++
+	if s.limits.MaxElements > 0 && s.elements > s.limits.MaxElements {
+		return ErrMaxElementsExceeded
+	}
+	return nil
+}
+
+// reserve check the length of the array or map before it is allocated, the length is controlled by javascript,
+// so that a huge sparse array could not exhaust the memory, the elements are still counted once converted
+func (s *conversionState) reserve(length int64) error {
+	if s.limits.MaxElements > 0 && int64(s.elements)+length > int64(s.limits.MaxElements) {
+		return ErrMaxElementsExceeded
+	}
+	return nil
+}
+
+func (s *conversionState) enter() error {
+	s.depth++
+	if s.limits.MaxDepth > 0 && s.depth > s.limits.MaxDepth {
+		return ErrMaxDepthExceeded
+	}
+	return nil
+}
+
+func (s *conversionState) leave() { s.depth-- }
+
+var reflectValueType = reflect.TypeOf(reflect.Value{})
+
+// visitKey identify the golang struct, map or slice, the type is required because
+// a struct and its first field share the address, and the slices of same array may have different length
+type visitKey struct {
+	ptr    uintptr
+	t      reflect.Type
+	length int
+}
+
+// jsConverter convert golang value to javascript value,
+// the same golang pointer, map or slice will be converted to the same javascript object
+type jsConverter struct {
+	conversionState
+	ctx     *Context
+	visited map[visitKey]Value
+}
+
+func (ctx *Context) newJSConverter() *jsConverter {
+	return &jsConverter{
+		conversionState: conversionState{limits: ctx.conversionLimits},
+		ctx:             ctx,
+		visited:         map[visitKey]Value{},
+	}
+}
+
+// Marshal convert golang value to javascript value, return error if the conversion exceeds the limits
+func (ctx *Context) Marshal(value interface{}) (Value, error) {
+	return ctx.newJSConverter().toJS(value)
+}
+
+// visit return the converted javascript object of key
+func (c *jsConverter) visit(key visitKey) (Value, bool) {
+	val, ok := c.visited[key]
+	if !ok {
+		return val, false
+	}
+	return val.Dup(), true
+}
+
+func (c *jsConverter) toJS(value interface{}) (Value, error) {
+	// if is reflect.Value, convert the real value
+	if reflectValue, ok := value.(reflect.Value); ok {
+		return c.reflectToJS(reflectValue)
+	}
+	return c.reflectToJS(reflect.ValueOf(value))
+}
+
+// reflectToJS convert the reflect.Value, the addressable structs are identified by their addresses
+func (c *jsConverter) reflectToJS(reflectValue reflect.Value) (Value, error) {
+	ctx := c.ctx
+
+	if reflectValue.Kind() == reflect.Interface {
+		reflectValue = reflectValue.Elem()
+	}
+	if !reflectValue.IsValid() {
+		return ctx.Undefined(), nil
+	}
+
+	reflectType := reflectValue.Type()
+
+	// if it is quickjs.Value, return it directly
+	if reflectType == valueType {
+		return reflectValue.Interface().(Value), nil
+	}
+	// if is reflect.Value (e.g. the item of []reflect.Value), convert the real value
+	if reflectType == reflectValueType {
+		return c.reflectToJS(reflectValue.Interface().(reflect.Value))
+	}
+
+	if err := c.count(); err != nil {
+		return ctx.Undefined(), err
+	}
+
+	if val, ok, err := c.converterToJS(reflectValue); ok {
+		return val, err
+	}
+
+	switch reflectType {
+	case timeType:
+		return ctx.Date(reflectValue.Interface().(time.Time)), nil
+	case durationType:
+		return ctx.Float64(durationToMilliseconds(time.Duration(reflectValue.Int()))), nil
+	case symbolType:
+		return ctx.symbol(reflectValue.Interface().(Symbol)), nil
+	case orderedMapType:
+		return c.jsMap(reflectValue.Interface().(OrderedMap))
+	case bigIntType:
+		value := reflectValue.Interface().(big.Int)
+		return ctx.BigInt(&value), nil
+	case bigFloatType:
+		value := reflectValue.Interface().(big.Float)
+		return ctx.BigFloat(&value), nil
+	case bigIntPtrType:
+		if reflectValue.IsNil() {
+			return ctx.Null(), nil
+		}
+		return ctx.BigInt(reflectValue.Interface().(*big.Int)), nil
+	case bigFloatPtrType:
+		if reflectValue.IsNil() {
+			return ctx.Null(), nil
+		}
+		return ctx.BigFloat(reflectValue.Interface().(*big.Float)), nil
+	}
+
+	if val, ok, err := c.marshalerToJS(reflectValue); ok {
+		return val, err
+	}
+
+	switch reflectValue.Kind() {
+	case reflect.String:
+		return ctx.String(reflectValue.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ctx.intToJS(reflectValue.Int(), reflectType.Bits()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ctx.uintToJS(reflectValue.Uint(), reflectType.Bits()), nil
+	case reflect.Float32, reflect.Float64:
+		return ctx.Float64(reflectValue.Float()), nil
+	case reflect.Bool:
+		return ctx.Bool(reflectValue.Bool()), nil
+	case reflect.Map:
+		if reflectValue.IsNil() {
+			return ctx.Null(), nil
+		}
+		key := visitKey{ptr: reflectValue.Pointer(), t: reflectType}
+		if val, ok := c.visit(key); ok {
+			return val, nil
+		}
+		if ctx.goMapAsJSMap {
+			return c.goMapToJSMap(key, reflectValue)
+		}
+		return c.goMapToObject(key, reflectValue)
+	case reflect.Struct:
+		return c.goStructToObject(reflectValue)
+	case reflect.Slice:
+		if reflectType.Elem().Kind() == reflect.Uint8 {
+			return ctx.typedArray(reflectValue, C.JS_TYPED_ARRAY_UINT8), nil
+		}
+		return c.goSliceToArray(reflectValue)
+	case reflect.Func:
+		return ctx.reflectFunction("", reflectValue), nil
+	case reflect.Ptr:
+		if reflectValue.IsNil() {
+			return ctx.Null(), nil
+		}
+		if classID, ok := ctx.classes[reflectType]; ok {
+			proto := ctx.newValue(C.JS_GetClassProto(ctx.ref, classID))
+			defer proto.Free()
+			return ctx.classInstance(classID, proto, reflectValue.Interface()), nil
+		}
+		return c.reflectToJS(reflectValue.Elem())
+	default:
+		// ignore
+	}
+	return ctx.Undefined(), nil
+}
+
+func (c *jsConverter) goStructToObject(reflectValue reflect.Value) (Value, error) {
+	ctx := c.ctx
+	reflectType := reflectValue.Type()
+
+	// only the addressable struct (referenced by pointer) could be shared
+	var key visitKey
+	if reflectValue.CanAddr() {
+		key = visitKey{ptr: reflectValue.UnsafeAddr(), t: reflectType}
+		if val, ok := c.visit(key); ok {
+			return val, nil
+		}
+	}
+
+	if err := c.enter(); err != nil {
+		return ctx.Undefined(), err
+	}
+	defer c.leave()
+
+	obj := ctx.Object()
+	if key.ptr != 0 {
+		c.visited[key] = obj
+	}
+	for _, f := range structFields(reflectType, ctx.namingPolicy) {
+		field, ok := fieldByIndex(reflectValue, f.index)
+		if !ok || (f.omitEmpty && field.IsZero()) {
+			continue
+		}
+		fieldValue, err := c.reflectToJS(field)
+		if err != nil {
+			obj.Free()
+			return ctx.Undefined(), err
+		}
+		obj.Set(f.name, fieldValue)
+	}
+	methodCount := reflectType.NumMethod()
+	for mIndex := 0; mIndex < methodCount; mIndex++ {
+		method := reflectValue.Method(mIndex)
+		methodName := ctx.propertyName(reflectType.Method(mIndex).Name)
+		obj.Set(methodName, ctx.reflectFunction(methodName, method))
+	}
+	return obj, nil
+}
+
+func (c *jsConverter) goSliceToArray(reflectValue reflect.Value) (Value, error) {
+	ctx := c.ctx
+
+	key := visitKey{ptr: reflectValue.Pointer(), t: reflectValue.Type(), length: reflectValue.Len()}
+	if val, ok := c.visit(key); ok {
+		return val, nil
+	}
+
+	if err := c.enter(); err != nil {
+		return ctx.Undefined(), err
+	}
+	defer c.leave()
+
+	obj := ctx.Array()
+	if key.ptr != 0 {
+		c.visited[key] = obj
+	}
+	for arrayItemIndex := 0; arrayItemIndex < reflectValue.Len(); arrayItemIndex++ {
+		arrayItem := reflectValue.Index(arrayItemIndex)
+		arrayItemValue, err := c.reflectToJS(arrayItem)
+		if err != nil {
+			obj.Free()
+			return ctx.Undefined(), err
+		}
+		obj.SetByInt64(int64(arrayItemIndex), arrayItemValue)
+	}
+	return obj, nil
+}
+
+// goConverter convert javascript value to golang value,
+// the same javascript object will be converted to the same golang map or slice
+type goConverter struct {
+	conversionState
+	visited map[unsafe.Pointer]interface{}
+}
+
+func (v Value) newGoConverter() *goConverter {
+	return &goConverter{
+		conversionState: conversionState{limits: v.ctx.conversionLimits},
+		visited:         map[unsafe.Pointer]interface{}{},
+	}
+}
+
+// Export convert javascript value to golang value as Value.Interface,
+// return error if the conversion exceeds the limits of Context
+func (v Value) Export() (interface{}, error) {
+	return v.newGoConverter().toGo(v)
+}
+
+func (c *goConverter) toGo(v Value) (interface{}, error) {
+	if err := c.count(); err != nil {
+		return nil, err
+	}
+
+	if v.isNumeric() {
+		return v.exportNumber(), nil
+	}
+	if v.IsString() {
+		return v.String(), nil
+	}
+	if v.IsUndefined() || v.IsNull() {
+		return nil, nil
+	}
+	if v.IsBool() {
+		return v.Bool(), nil
+	}
+	if v.IsSymbol() {
+		return v.Symbol(), nil
+	}
+	if v.IsError() {
+		return v.Error(), nil
+	}
+	if v.IsDate() {
+		return v.Time(), nil
+	}
+	if v.IsArrayBuffer() || v.IsDataView() {
+		return v.Bytes(), nil
+	}
+	if v.IsTypedArray() {
+		return v.typedArraySlice(), nil
+	}
+	if goValue, ok := v.goClassInstance(); ok {
+		return goValue, nil
+	}
+
+	// function also will be an object, just return function firstly,
+	// the function is referenced until the golang function is garbage collected or the context is freed
+	if v.IsFunction() {
+		ref := v.ctx.reference(v.Dup())
+		return func(args ...interface{}) interface{} {
+			defer stdruntime.KeepAlive(ref)
+			fn, err := ref.value()
+			if err != nil {
+				return err
+			}
+			var jsArgs []Value
+			// free all generated js args
+			defer func() {
+				for i, jsArg := range jsArgs {
+					if jsArg != args[i] {
+						jsArg.Free()
+					}
+				}
+			}()
+			for _, arg := range args {
+				jsArg, err := fn.ctx.Marshal(arg)
+				if err != nil {
+					return err
+				}
+				jsArgs = append(jsArgs, jsArg)
+			}
+			result := fn.Call(jsArgs...)
+
+			if result.IsException() {
+				return fn.ctx.Exception()
+			}
+
+			return result.InterfaceAndFree()
+		}, nil
+	}
+
+	if !v.IsObject() {
+		return nil, nil
+	}
+
+	ptr := C.GetValuePtr(v.ref)
+	if goValue, ok := c.visited[ptr]; ok {
+		return goValue, nil
+	}
+
+	if err := c.enter(); err != nil {
+		return nil, err
+	}
+	defer c.leave()
+
+	switch {
+	case v.IsMap():
+		return c.jsMapToOrderedMap(ptr, v)
+	case v.IsSet():
+		return c.jsSetToSlice(ptr, v)
+	case v.IsArray():
+		return c.jsArrayToSlice(ptr, v)
+	}
+
+	rt := map[string]interface{}{}
+	c.visited[ptr] = rt
+	if names, err := v.PropertyNames(); err == nil {
+		for _, name := range names {
+			propertyKey := name.String()
+			propertyValue := v.Get(propertyKey)
+			if propertyValue.IsUndefined() {
+				continue
+			}
+			goValue, err := c.toGo(propertyValue)
+			propertyValue.Free()
+			if err != nil {
+				return nil, err
+			}
+			rt[propertyKey] = goValue
+		}
+	}
+	return rt, nil
+}
+
+func (c *goConverter) jsArrayToSlice(ptr unsafe.Pointer, v Value) (interface{}, error) {
+	arrayLen := v.Len()
+	if arrayLen == 0 {
+		return []interface{}(nil), nil
+	}
+	if err := c.reserve(arrayLen); err != nil {
+		return nil, err
+	}
+	rt := make([]interface{}, arrayLen)
+	c.visited[ptr] = rt
+	for idx := int64(0); idx < arrayLen; idx++ {
+		item := v.GetByUint32(uint32(idx))
+		goValue, err := c.toGo(item)
+		item.Free()
+		if err != nil {
+			return nil, err
+		}
+		rt[idx] = goValue
+	}
+	return rt, nil
+}
@@ -0,0 +1,184 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	stdruntime "runtime"
+	"testing"
+	"time"
+)
+
+type DemoNode struct {
+	Name     string
+	Next     *DemoNode
+	Children []*DemoNode
+	Labels   map[string]string
+}
+
+func TestContext_MarshalCycles(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	labels := map[string]string{"env": "test"}
+	root := &DemoNode{Name: "root", Labels: labels}
+	child := &DemoNode{Name: "child", Next: root, Labels: labels}
+	root.Next = root
+	root.Children = []*DemoNode{child, child}
+
+	ctx.Globals().SetGoValue("root", root)
+	v, err := ctx.EvalGlobal(`[
+	root.Next === root,
+	root.Children[0] === root.Children[1],
+	root.Children[0].Next === root,
+	root.Labels === root.Children[0].Labels,
+	root.Children[0].Labels.env,
+]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{true, true, true, true, "test"}, v.InterfaceAndFree())
+}
+
+func TestValue_ExportCycles(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	v, err := ctx.EvalGlobal(`
+var a = { name: "a" };
+var shared = { x: 1 };
+a.self = a;
+a.list = [a, shared, shared];
+a.map = new Map([["a", a]]);
+a`)
+	require.NoError(t, err)
+	goValue, err := v.Export()
+	v.Free()
+	require.NoError(t, err)
+
+	a := goValue.(map[string]interface{})
+	assert.Equal("a", a["name"])
+	assert.Equal(reflect.ValueOf(a).Pointer(), reflect.ValueOf(a["self"]).Pointer())
+	list := a["list"].([]interface{})
+	assert.Equal(reflect.ValueOf(a).Pointer(), reflect.ValueOf(list[0]).Pointer())
+	assert.Equal(reflect.ValueOf(list[1]).Pointer(), reflect.ValueOf(list[2]).Pointer())
+	mapped, ok := a["map"].(OrderedMap).Get("a")
+	assert.True(ok)
+	assert.Equal(reflect.ValueOf(a).Pointer(), reflect.ValueOf(mapped).Pointer())
+
+	v, err = ctx.EvalGlobal(`var list = [1]; list.push(list); list`)
+	require.NoError(t, err)
+	s := v.InterfaceAndFree().([]interface{})
+	assert.Equal(int64(1), s[0])
+	assert.Equal(reflect.ValueOf(s).Pointer(), reflect.ValueOf(s[1]).Pointer())
+}
+
+func TestContext_SetConversionLimits(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	ctx.SetConversionLimits(ConversionLimits{MaxDepth: 2, MaxElements: 10})
+
+	_, err := ctx.Marshal(map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{}}})
+	assert.Equal(ErrMaxDepthExceeded, err)
+	_, err = ctx.Marshal(make([]int, 10))
+	assert.Equal(ErrMaxElementsExceeded, err)
+
+	val, err := ctx.Marshal(map[string]interface{}{"a": []int{1, 2}})
+	require.NoError(t, err)
+	val.Free()
+
+	val = ctx.ToJSValue(make([]string, 20))
+	assert.True(val.IsUndefined())
+
+	ctx.Globals().SetGoValue("echo", func(v interface{}) interface{} { return v })
+	_, err = ctx.EvalGlobal(`echo({ a: { b: { c: 1 } } })`)
+	assert.Error(err)
+	ctx.Globals().SetGoValue("large", func() []string { return make([]string, 20) })
+	_, err = ctx.EvalGlobal(`large()`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "RangeError: conversion exceeds the max elements")
+
+	// the exported function outlives the value
+	v, err := ctx.EvalGlobal(`(a, b) => a + b`)
+	require.NoError(t, err)
+	add := v.InterfaceAndFree().(func(args ...interface{}) interface{})
+	assert.Equal(int64(5), add(2, 3))
+	assert.Equal(ErrMaxElementsExceeded, add(make([]int, 20), 1))
+
+	v, err = ctx.EvalGlobal(`[[[1]]]`)
+	require.NoError(t, err)
+	_, err = v.Export()
+	assert.Equal(ErrMaxDepthExceeded, err)
+	assert.Nil(v.Interface())
+	v.Free()
+
+	v, err = ctx.EvalGlobal(`new Array(20).fill(0)`)
+	require.NoError(t, err)
+	_, err = v.Export()
+	assert.Equal(ErrMaxElementsExceeded, err)
+	v.Free()
+
+	// the length of the huge sparse array is checked before the slice is allocated
+	v, err = ctx.EvalGlobal(`var sparse = []; sparse.length = 4294967295; sparse`)
+	require.NoError(t, err)
+	_, err = v.Export()
+	assert.Equal(ErrMaxElementsExceeded, err)
+	v.Free()
+}
+
+// collectReferences run the garbage collections until the references of ctx are released,
+// and return the number of the references left
+func collectReferences(r Runtime, ctx *Context) int {
+	for i := 0; i < 100; i++ {
+		stdruntime.GC()
+		r.RunGC()
+		if len(ctx.references.values) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return len(ctx.references.values)
+}
+
+func TestValue_ExportedFunctionLifetime(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+
+	v, err := ctx.EvalGlobal(`({ f(a) { return a * 2 } })`)
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		exported := v.Interface().(map[string]interface{})
+		assert.Equal(int64(2), exported["f"].(func(args ...interface{}) interface{})(1))
+	}
+	// the functions are released once collected by golang
+	assert.Equal(0, collectReferences(r, ctx))
+
+	f := v.Interface().(map[string]interface{})["f"].(func(args ...interface{}) interface{})
+	assert.Len(ctx.references.values, 1)
+	assert.Equal(int64(4), f(2))
+	v.Free()
+	ctx.Free()
+	assert.Equal(ErrContextFreed, f(2))
+}
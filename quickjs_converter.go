@@ -0,0 +1,121 @@
+package quickjs
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+// Converter convert the golang values of a specific type between golang and javascript,
+// ToJS or FromJS could be nil to keep the default conversion of that direction
+type Converter struct {
+	// ToJS convert the golang value to javascript value, return the thrown exception to report the error
+	ToJS func(ctx *Context, value interface{}) Value
+	// FromJS convert the javascript value to golang value, the result must be assignable to the type
+	FromJS func(v Value) (interface{}, error)
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// RegisterConverter register the converter of golang type for all contexts of the runtime,
+// the converters registered by Context.RegisterConverter take precedence
+func (r Runtime) RegisterConverter(t reflect.Type, converter Converter) {
+	if r.state.converters == nil {
+		r.state.converters = map[reflect.Type]Converter{}
+	}
+	r.state.converters[t] = converter
+}
+
+// RegisterConverter register the converter of golang type for the context, it is used by
+// Context.ToJSValue, Value.Decode, Value.ToReflectValue and the arguments of golang functions,
+// the types without converter fallback to json.Marshaler/json.Unmarshaler and encoding.TextMarshaler/TextUnmarshaler
+func (ctx *Context) RegisterConverter(t reflect.Type, converter Converter) {
+	if ctx.converters == nil {
+		ctx.converters = map[reflect.Type]Converter{}
+	}
+	ctx.converters[t] = converter
+}
+
+// converter lookup the converter of the context, then the runtime
+func (ctx *Context) converter(t reflect.Type) (Converter, bool) {
+	if converter, ok := ctx.converters[t]; ok {
+		return converter, true
+	}
+	converter, ok := ctx.runtime.state.converters[t]
+	return converter, ok
+}
+
+// customDecoding return true if the values of t are decoded by converter or unmarshaler
+func (ctx *Context) customDecoding(t reflect.Type) bool {
+	if converter, ok := ctx.converter(t); ok && converter.FromJS != nil {
+		return true
+	}
+	ptrType := reflect.PtrTo(t)
+	return ptrType.Implements(unmarshalerType) || ptrType.Implements(jsonUnmarshalerType) || ptrType.Implements(textUnmarshalerType)
+}
+
+// customEncoding return true if the values of t are converted by converter, marshaler or as the javascript
+// builtin values (e.g. time.Time to Date, big.Int to BigInt) instead of the fields, as Context.Marshal does
+func (ctx *Context) customEncoding(t reflect.Type) bool {
+	switch t {
+	case timeType, symbolType, bigIntType, bigFloatType:
+		return true
+	}
+	if converter, ok := ctx.converter(t); ok && converter.ToJS != nil {
+		return true
+	}
+	ptrType := reflect.PtrTo(t)
+	return ptrType.Implements(jsonMarshalerType) || ptrType.Implements(textMarshalerType)
+}
+
+// converterToJS convert the golang value with the registered converter, return false if the type has no converter
+func (c *jsConverter) converterToJS(reflectValue reflect.Value) (Value, bool, error) {
+	ctx := c.ctx
+	converter, ok := ctx.converter(reflectValue.Type())
+	if !ok || converter.ToJS == nil || !reflectValue.CanInterface() {
+		return ctx.Undefined(), false, nil
+	}
+	val := converter.ToJS(ctx, reflectValue.Interface())
+	if val.IsException() {
+		return val, true, ctx.Exception()
+	}
+	return val, true, nil
+}
+
+// marshalerToJS convert the golang value implementing json.Marshaler or encoding.TextMarshaler,
+// the pointer receivers are used if the value is addressable as encoding/json does
+func (c *jsConverter) marshalerToJS(reflectValue reflect.Value) (Value, bool, error) {
+	ctx := c.ctx
+	if !reflectValue.CanInterface() || (reflectValue.Kind() == reflect.Ptr && reflectValue.IsNil()) {
+		return ctx.Undefined(), false, nil
+	}
+	if _, isClass := ctx.classes[reflectValue.Type()]; isClass {
+		return ctx.Undefined(), false, nil
+	}
+
+	goValue := reflectValue.Interface()
+	if reflectValue.Kind() != reflect.Ptr && reflectValue.CanAddr() {
+		goValue = reflectValue.Addr().Interface()
+	}
+	switch marshaler := goValue.(type) {
+	case json.Marshaler:
+		data, err := marshaler.MarshalJSON()
+		if err != nil {
+			return ctx.Undefined(), true, err
+		}
+		val := ctx.ParseJson(string(data))
+		if val.IsException() {
+			return val, true, ctx.Exception()
+		}
+		return val, true, nil
+	case encoding.TextMarshaler:
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return ctx.Undefined(), true, err
+		}
+		return ctx.String(string(text)), true, nil
+	}
+	return ctx.Undefined(), false, nil
+}
@@ -0,0 +1,134 @@
+package quickjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/url"
+	"reflect"
+	stdruntime "runtime"
+	"testing"
+)
+
+// DemoMoney in cents
+type DemoMoney int64
+
+type DemoPoint struct {
+	X, Y int
+}
+
+func (p DemoPoint) MarshalJSON() ([]byte, error) { return json.Marshal([]int{p.X, p.Y}) }
+
+func (p *DemoPoint) UnmarshalJSON(data []byte) error {
+	var xy []int
+	if err := json.Unmarshal(data, &xy); err != nil {
+		return err
+	}
+	if len(xy) != 2 {
+		return fmt.Errorf("point requires 2 numbers, got %d", len(xy))
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+type DemoOrder struct {
+	Price    DemoMoney
+	Host     net.IP
+	Callback *url.URL
+	At       DemoPoint
+}
+
+func TestContext_RegisterConverter(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	r.RegisterConverter(reflect.TypeOf(DemoMoney(0)), Converter{
+		ToJS: func(ctx *Context, value interface{}) Value {
+			cents := value.(DemoMoney)
+			return ctx.String(fmt.Sprintf("%d.%02d", cents/100, cents%100))
+		},
+		FromJS: func(v Value) (interface{}, error) {
+			var units, cents int64
+			if _, err := fmt.Sscanf(v.String(), "%d.%02d", &units, &cents); err != nil {
+				return nil, err
+			}
+			return DemoMoney(units*100 + cents), nil
+		},
+	})
+	r.RegisterConverter(reflect.TypeOf(&url.URL{}), Converter{
+		ToJS: func(ctx *Context, value interface{}) Value {
+			return ctx.String(value.(*url.URL).String())
+		},
+		FromJS: func(v Value) (interface{}, error) { return url.Parse(v.String()) },
+	})
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	order := DemoOrder{
+		Price:    1250,
+		Host:     net.ParseIP("10.0.0.1"),
+		Callback: &url.URL{Scheme: "https", Host: "example.com", Path: "/hook"},
+		At:       DemoPoint{X: 1, Y: 2},
+	}
+	ctx.Globals().SetGoValue("order", order)
+	v, err := ctx.EvalGlobal(`[order.Price, order.Host, order.Callback, order.At]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"12.50", "10.0.0.1", "https://example.com/hook", []interface{}{int64(1), int64(2)}}, v.InterfaceAndFree())
+
+	v, err = ctx.EvalGlobal(`({ Price: "3.05", Host: "::1", Callback: "http://localhost/cb", At: [3, 4] })`)
+	require.NoError(t, err)
+	decoded := &DemoOrder{}
+	require.NoError(t, v.Decode(decoded))
+	v.Free()
+	assert.Equal(DemoMoney(305), decoded.Price)
+	assert.Equal(net.ParseIP("::1"), decoded.Host)
+	assert.Equal("http://localhost/cb", decoded.Callback.String())
+	assert.Equal(DemoPoint{X: 3, Y: 4}, decoded.At)
+
+	v, err = ctx.EvalGlobal(`({ At: [1] })`)
+	require.NoError(t, err)
+	err = v.Decode(decoded)
+	v.Free()
+	assert.EqualError(err, "decode 'At': point requires 2 numbers, got 1")
+
+	// the converters are used for golang function arguments
+	ctx.Globals().SetGoValue("total", func(prices []DemoMoney, host net.IP) DemoMoney {
+		var sum DemoMoney
+		for _, price := range prices {
+			sum += price
+		}
+		if host.IsLoopback() {
+			sum *= 2
+		}
+		return sum
+	})
+	v, err = ctx.EvalGlobal(`total(["1.50", "2.25"], "127.0.0.1")`)
+	require.NoError(t, err)
+	assert.Equal("7.50", v.InterfaceAndFree())
+	_, err = ctx.EvalGlobal(`total(["one"], "127.0.0.1")`)
+	assert.Error(err)
+
+	// the converters of context override the converters of runtime
+	other := r.NewContext()
+	defer other.Free()
+	other.RegisterConverter(reflect.TypeOf(DemoMoney(0)), Converter{
+		ToJS: func(ctx *Context, value interface{}) Value { return ctx.Int64(int64(value.(DemoMoney))) },
+	})
+	v, err = other.Marshal(DemoMoney(99))
+	require.NoError(t, err)
+	assert.Equal(int64(99), v.InterfaceAndFree())
+	v, err = ctx.Marshal(DemoMoney(99))
+	require.NoError(t, err)
+	assert.Equal("0.99", v.InterfaceAndFree())
+
+	other.RegisterConverter(reflect.TypeOf(DemoPoint{}), Converter{
+		ToJS: func(ctx *Context, value interface{}) Value { return ctx.ThrowTypeError("unsupported point") },
+	})
+	_, err = other.Marshal(DemoPoint{})
+	assert.EqualError(err, "TypeError: unsupported point")
+}
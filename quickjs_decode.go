@@ -0,0 +1,878 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"math"
+	"math/big"
+	"reflect"
+	stdruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Unmarshaler is implemented by the types which decode themselves from javascript value in Value.Decode
+type Unmarshaler interface {
+	UnmarshalJS(v Value) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// DecodeOptions of Value.DecodeWithOptions
+type DecodeOptions struct {
+	// Strict reject the unknown keys of objects, the missing required fields and the lossy number conversions
+	// (fractions to integers and the precision loss of floats), the number overflows are always rejected,
+	// the fields are required unless they are pointers, interfaces, quickjs.Value or tagged with `omitempty`
+	Strict bool
+	// DecodeHook is the mapstructure decode hook invoked before decoding every value,
+	// the data passed to the hook is the golang value returned by Value.Interface,
+	// the value returned by the hook is decoded by mapstructure if it is changed
+	DecodeHook mapstructure.DecodeHookFunc
+}
+
+// DecodeError is returned by Value.Decode, Path is the property path of the value failed to decode, e.g. `a.b[0]`
+type DecodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("decode: %v", e.Err)
+	}
+	return fmt.Sprintf("decode '%s': %v", e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Decode js Value to golang value, the target must be a non-nil pointer,
+// the decoded quickjs.Value (e.g. the Value fields of struct) must be freed by the caller,
+// the javascript functions referenced by the decoded golang functions are released once the golang functions are garbage collected or the Context is freed
+func (v Value) Decode(target interface{}) error {
+	return v.DecodeWithOptions(target, DecodeOptions{})
+}
+
+// DecodeWithOptions decode js Value to golang value with options, see Value.Decode
+func (v Value) DecodeWithOptions(target interface{}, options DecodeOptions) error {
+	reflectValue := reflect.ValueOf(target)
+	if reflectValue.Kind() != reflect.Ptr || reflectValue.IsNil() {
+		return &DecodeError{Err: fmt.Errorf("target must be a non-nil pointer, got %T", target)}
+	}
+	return v.newDecoder(options).decode(v, reflectValue.Elem(), "")
+}
+
+// decodeKey identify the decoded javascript object with the golang type
+type decodeKey struct {
+	ptr unsafe.Pointer
+	t   reflect.Type
+}
+
+// decoder decode javascript value to golang value of the given type,
+// the same javascript object will be decoded to the same golang pointer, map or slice
+type decoder struct {
+	conversionState
+	ctx     *Context
+	options DecodeOptions
+	visited map[decodeKey]reflect.Value
+	// borrow the decoded quickjs.Value instead of duplicating them, they are valid until the root value is freed
+	borrow bool
+	// hookData convert the values passed to the decode hook, the nested objects converted with
+	// the root are reused, so that each object is converted once
+	hookData *goConverter
+}
+
+func (v Value) newDecoder(options DecodeOptions) *decoder {
+	return &decoder{
+		conversionState: conversionState{limits: v.ctx.conversionLimits},
+		ctx:             v.ctx,
+		options:         options,
+		visited:         map[decodeKey]reflect.Value{},
+	}
+}
+
+func (d *decoder) errorf(path string, format string, args ...interface{}) error {
+	return &DecodeError{Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+func (d *decoder) mismatch(v Value, target reflect.Value, path string) error {
+	return d.errorf(path, "can not decode %s to %s", v.TypeOf(), target.Type())
+}
+
+// lossy return the error of lossy number conversion in strict mode
+func (d *decoder) lossy(v Value, target reflect.Value, path string) error {
+	if !d.options.Strict {
+		return nil
+	}
+	return d.errorf(path, "can not decode %s to %s without loss", v.String(), target.Type())
+}
+
+func (d *decoder) decode(v Value, target reflect.Value, path string) error {
+	if err := d.count(); err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+
+	if d.options.DecodeHook != nil && !v.IsUndefined() && !v.IsNull() {
+		if decoded, err := d.decodeHook(v, target, path); decoded || err != nil {
+			return err
+		}
+	}
+
+	if converter, ok := d.ctx.converter(target.Type()); ok && converter.FromJS != nil {
+		goValue, err := converter.FromJS(v)
+		if err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		if goValue == nil {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		if !reflect.TypeOf(goValue).AssignableTo(target.Type()) {
+			return d.errorf(path, "converter of %s returned %T", target.Type(), goValue)
+		}
+		target.Set(reflect.ValueOf(goValue))
+		return nil
+	}
+
+	if target.CanAddr() && target.Addr().Type().Implements(unmarshalerType) {
+		if err := target.Addr().Interface().(Unmarshaler).UnmarshalJS(v); err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		return nil
+	}
+
+	targetType := target.Type()
+
+	switch targetType {
+	case valueType:
+		if !d.borrow {
+			v = v.Dup()
+		}
+		target.Set(reflect.ValueOf(v))
+		return nil
+	case timeType:
+		return d.decodeTime(v, target, path)
+	case durationType:
+		return d.decodeDuration(v, target, path)
+	case bigIntType, bigFloatType:
+		return d.decodeBig(v, target, path)
+	}
+
+	if v.IsUndefined() || v.IsNull() {
+		target.Set(reflect.Zero(targetType))
+		return nil
+	}
+
+	if target.CanAddr() && target.Addr().Type().Implements(jsonUnmarshalerType) {
+		if err := target.Addr().Interface().(json.Unmarshaler).UnmarshalJSON([]byte(v.ToJsonString())); err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		return nil
+	}
+	if v.IsString() && target.CanAddr() && target.Addr().Type().Implements(textUnmarshalerType) {
+		if err := target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v.String())); err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		return nil
+	}
+
+	switch targetType.Kind() {
+	case reflect.Interface:
+		goValue, err := v.newGoConverter().toGo(v)
+		if err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		if goValue == nil {
+			target.Set(reflect.Zero(targetType))
+			return nil
+		}
+		if !reflect.TypeOf(goValue).AssignableTo(targetType) {
+			return d.mismatch(v, target, path)
+		}
+		target.Set(reflect.ValueOf(goValue))
+	case reflect.Bool:
+		if !v.IsBool() {
+			return d.mismatch(v, target, path)
+		}
+		target.SetBool(v.Bool())
+	case reflect.String:
+		if !v.IsString() {
+			return d.mismatch(v, target, path)
+		}
+		target.SetString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return d.decodeInt(v, target, path)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return d.decodeUint(v, target, path)
+	case reflect.Float32, reflect.Float64:
+		return d.decodeFloat(v, target, path)
+	case reflect.Ptr:
+		return d.decodePtr(v, target, path)
+	case reflect.Struct:
+		return d.decodeStruct(v, target, path)
+	case reflect.Map:
+		return d.decodeMap(v, target, path)
+	case reflect.Slice:
+		return d.decodeSlice(v, target, path)
+	case reflect.Array:
+		return d.decodeArray(v, target, path)
+	case reflect.Func:
+		return d.decodeFunc(v, target, path)
+	default:
+		return d.mismatch(v, target, path)
+	}
+	return nil
+}
+
+// decodeHook invoke the decode hook, return true if the value is changed by hook and decoded by mapstructure
+func (d *decoder) decodeHook(v Value, target reflect.Value, path string) (bool, error) {
+	if d.hookData == nil {
+		d.hookData = v.newGoConverter()
+	}
+	// the elements are limited by the decoder, the nested values converted before are counted once here
+	d.hookData.elements = 0
+	data, err := d.hookData.toGo(v)
+	if err != nil {
+		return false, &DecodeError{Path: path, Err: err}
+	}
+	from := reflect.TypeOf(data)
+	if from == nil {
+		return false, nil
+	}
+	result, err := mapstructure.DecodeHookExec(d.options.DecodeHook, from, target.Type(), data)
+	if err != nil {
+		return false, &DecodeError{Path: path, Err: err}
+	}
+	if reflect.TypeOf(result) == from && (!from.Comparable() || result == data) {
+		return false, nil
+	}
+
+	// the hook has been invoked with the root value, only the nested values are passed to it by mapstructure
+	root := true
+	nestedHook := func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if root {
+			root = false
+			return data, nil
+		}
+		return mapstructure.DecodeHookExec(d.options.DecodeHook, from, to, data)
+	}
+	md, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  mapstructure.ComposeDecodeHookFunc(nestedHook, timeDecodeHook, mapDecodeHook),
+		ErrorUnused: d.options.Strict,
+		Result:      target.Addr().Interface(),
+	})
+	if err == nil {
+		err = md.Decode(result)
+	}
+	if err != nil {
+		return true, &DecodeError{Path: path, Err: err}
+	}
+	return true, nil
+}
+
+func (d *decoder) decodeTime(v Value, target reflect.Value, path string) error {
+	switch {
+	case v.IsDate():
+		target.Set(reflect.ValueOf(v.Time()))
+	case v.IsNumber():
+		target.Set(reflect.ValueOf(time.Unix(0, 0).Add(millisecondsToDuration(v.Float64()))))
+	case v.IsString():
+		t, err := time.Parse(time.RFC3339Nano, v.String())
+		if err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		target.Set(reflect.ValueOf(t))
+	case v.IsUndefined() || v.IsNull():
+		target.Set(reflect.Zero(timeType))
+	default:
+		return d.mismatch(v, target, path)
+	}
+	return nil
+}
+
+func (d *decoder) decodeDuration(v Value, target reflect.Value, path string) error {
+	switch {
+	case v.IsNumber():
+		target.SetInt(int64(v.Duration()))
+	case v.IsString():
+		duration, err := time.ParseDuration(v.String())
+		if err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		target.SetInt(int64(duration))
+	case v.IsUndefined() || v.IsNull():
+		target.SetInt(0)
+	default:
+		return d.mismatch(v, target, path)
+	}
+	return nil
+}
+
+// number return the exact value of javascript Number, BigInt, BigFloat or BigDecimal
+func (d *decoder) number(v Value, target reflect.Value, path string) (*big.Float, error) {
+	switch {
+	case v.IsBigInt():
+		return new(big.Float).SetInt(v.BigInt()), nil
+	case v.IsBigFloat() || v.IsBigDecimal():
+		if x := v.BigFloat(); x != nil {
+			return x, nil
+		}
+	case v.IsNumber():
+		if f := v.Float64(); !math.IsNaN(f) {
+			return big.NewFloat(f), nil
+		}
+	default:
+		return nil, d.mismatch(v, target, path)
+	}
+	return nil, d.errorf(path, "can not decode NaN to %s", target.Type())
+}
+
+func (d *decoder) overflow(v Value, target reflect.Value, path string) error {
+	return d.errorf(path, "%s overflows %s", v.String(), target.Type())
+}
+
+// integer return the integer part of number, the fractional part is lossy in strict mode
+func (d *decoder) integer(v Value, target reflect.Value, path string) (*big.Int, error) {
+	x, err := d.number(v, target, path)
+	if err != nil {
+		return nil, err
+	}
+	if x.IsInf() {
+		return nil, d.overflow(v, target, path)
+	}
+	if !x.IsInt() {
+		if err := d.lossy(v, target, path); err != nil {
+			return nil, err
+		}
+	}
+	i, _ := x.Int(nil)
+	return i, nil
+}
+
+// decodeBig decode numbers to big.Int or big.Float, and the strings are parsed
+func (d *decoder) decodeBig(v Value, target reflect.Value, path string) error {
+	if v.IsUndefined() || v.IsNull() {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	var x *big.Float
+	if v.IsString() {
+		var ok bool
+		if x, ok = new(big.Float).SetString(v.String()); !ok {
+			return d.mismatch(v, target, path)
+		}
+	} else {
+		var err error
+		if x, err = d.number(v, target, path); err != nil {
+			return err
+		}
+	}
+
+	if target.Type() == bigFloatType {
+		target.Set(reflect.ValueOf(*x))
+		return nil
+	}
+	if x.IsInf() {
+		return d.overflow(v, target, path)
+	}
+	if !x.IsInt() {
+		if err := d.lossy(v, target, path); err != nil {
+			return err
+		}
+	}
+	i, _ := x.Int(nil)
+	target.Set(reflect.ValueOf(*i))
+	return nil
+}
+
+func (d *decoder) decodeInt(v Value, target reflect.Value, path string) error {
+	i, err := d.integer(v, target, path)
+	if err != nil {
+		return err
+	}
+	if !i.IsInt64() || target.OverflowInt(i.Int64()) {
+		return d.overflow(v, target, path)
+	}
+	target.SetInt(i.Int64())
+	return nil
+}
+
+func (d *decoder) decodeUint(v Value, target reflect.Value, path string) error {
+	i, err := d.integer(v, target, path)
+	if err != nil {
+		return err
+	}
+	if !i.IsUint64() || target.OverflowUint(i.Uint64()) {
+		return d.overflow(v, target, path)
+	}
+	target.SetUint(i.Uint64())
+	return nil
+}
+
+func (d *decoder) decodeFloat(v Value, target reflect.Value, path string) error {
+	var f float64
+	if v.IsNumber() {
+		f = v.Float64()
+	} else {
+		x, err := d.number(v, target, path)
+		if err != nil {
+			return err
+		}
+		var accuracy big.Accuracy
+		f, accuracy = x.Float64()
+		if math.IsInf(f, 0) && !x.IsInf() {
+			return d.overflow(v, target, path)
+		}
+		if accuracy != big.Exact {
+			if err := d.lossy(v, target, path); err != nil {
+				return err
+			}
+		}
+	}
+	if target.Kind() == reflect.Float32 && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		if target.OverflowFloat(f) {
+			return d.overflow(v, target, path)
+		}
+		if float64(float32(f)) != f {
+			if err := d.lossy(v, target, path); err != nil {
+				return err
+			}
+		}
+	}
+	target.SetFloat(f)
+	return nil
+}
+
+func (d *decoder) decodePtr(v Value, target reflect.Value, path string) error {
+	targetType := target.Type()
+	if goValue, ok := v.goClassInstance(); ok && reflect.TypeOf(goValue) == targetType {
+		target.Set(reflect.ValueOf(goValue))
+		return nil
+	}
+
+	var key decodeKey
+	if v.getTag() == JsTagOBJECT {
+		key = decodeKey{ptr: C.GetValuePtr(v.ref), t: targetType}
+		if ptr, ok := d.visited[key]; ok {
+			target.Set(ptr)
+			return nil
+		}
+	}
+
+	ptr := reflect.New(targetType.Elem())
+	if key.ptr != nil {
+		d.visited[key] = ptr
+	}
+	if err := d.decode(v, ptr.Elem(), path); err != nil {
+		return err
+	}
+	target.Set(ptr)
+	return nil
+}
+
+// propertyKeys return the enumerable string keys of object
+func (v Value) propertyKeys() []string {
+	names, err := v.PropertyNames()
+	if err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		if !name.IsEnumerable {
+			continue
+		}
+		atomValue := name.Atom.Value()
+		isSymbol := atomValue.IsSymbol()
+		atomValue.Free()
+		if !isSymbol {
+			keys = append(keys, name.String())
+		}
+	}
+	return keys
+}
+
+func joinPath(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+// fieldByIndexAlloc same as fieldByIndex, but allocate the nil embedded pointers,
+// return false if the embedded pointer is not settable (unexported)
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fIndex := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fIndex)
+	}
+	return v, true
+}
+
+func (d *decoder) decodeStruct(v Value, target reflect.Value, path string) error {
+	if !v.IsObject() || v.IsArray() {
+		return d.mismatch(v, target, path)
+	}
+	if err := d.enter(); err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+	defer d.leave()
+
+	targetType := target.Type()
+	keys := v.propertyKeys()
+	used := map[string]bool{}
+	var missing []string
+
+	for _, f := range structFields(targetType, d.ctx.namingPolicy) {
+		key := f.name
+		own, err := v.hasOwnProperty(key)
+		if err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		// the inherited properties (e.g. `toString`) are not the fields of object
+		if !own {
+			// match the keys case-insensitively as mapstructure does
+			key = ""
+			for _, k := range keys {
+				if strings.EqualFold(k, f.name) {
+					key = k
+					break
+				}
+			}
+		}
+		if len(key) == 0 {
+			if d.options.Strict && !f.omitEmpty && !isOptionalType(targetType.FieldByIndex(f.index).Type) {
+				missing = append(missing, f.name)
+			}
+			continue
+		}
+		used[key] = true
+
+		field, ok := fieldByIndexAlloc(target, f.index)
+		if !ok {
+			continue
+		}
+		fieldValue := v.Get(key)
+		err = d.decode(fieldValue, field, joinPath(path, key))
+		fieldValue.Free()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !d.options.Strict {
+		return nil
+	}
+	if len(missing) > 0 {
+		return d.errorf(path, "missing required fields '%s'", strings.Join(missing, "', '"))
+	}
+	var unknown []string
+	for _, k := range keys {
+		if !used[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		return d.errorf(path, "unknown keys '%s'", strings.Join(unknown, "', '"))
+	}
+	return nil
+}
+
+// decodeMapKey decode the string key of object to the key type of map
+func (d *decoder) decodeMapKey(key string, target reflect.Value, path string) error {
+	if target.CanAddr() && target.Addr().Type().Implements(textUnmarshalerType) {
+		if err := target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return &DecodeError{Path: path, Err: err}
+		}
+		return nil
+	}
+	var err error
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(key)
+	case reflect.Interface:
+		target.Set(reflect.ValueOf(key))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(key, 10, target.Type().Bits()); err == nil {
+			target.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(key, 10, target.Type().Bits()); err == nil {
+			target.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(key, target.Type().Bits()); err == nil {
+			target.SetFloat(f)
+		}
+	default:
+		err = fmt.Errorf("can not decode key '%s' to %s", key, target.Type())
+	}
+	if err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(v Value, target reflect.Value, path string) error {
+	if !v.IsObject() || v.IsArray() {
+		return d.mismatch(v, target, path)
+	}
+	targetType := target.Type()
+	key := decodeKey{ptr: C.GetValuePtr(v.ref), t: targetType}
+	if m, ok := d.visited[key]; ok {
+		target.Set(m)
+		return nil
+	}
+	if err := d.enter(); err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+	defer d.leave()
+
+	m := reflect.MakeMap(targetType)
+	d.visited[key] = m
+	target.Set(m)
+
+	if v.IsMap() {
+		index := 0
+		var err error
+		forEachErr := v.forEach(func(value, key Value) {
+			if err != nil {
+				return
+			}
+			entryPath := fmt.Sprintf("%s[%d]", path, index)
+			index++
+			mapKey := reflect.New(targetType.Key()).Elem()
+			if err = d.decode(key, mapKey, entryPath); err != nil {
+				return
+			}
+			mapValue := reflect.New(targetType.Elem()).Elem()
+			if err = d.decode(value, mapValue, entryPath); err == nil {
+				m.SetMapIndex(mapKey, mapValue)
+			}
+		})
+		if err == nil && forEachErr != nil {
+			err = &DecodeError{Path: path, Err: forEachErr}
+		}
+		return err
+	}
+
+	for _, k := range v.propertyKeys() {
+		keyPath := joinPath(path, k)
+		mapKey := reflect.New(targetType.Key()).Elem()
+		if err := d.decodeMapKey(k, mapKey, keyPath); err != nil {
+			return err
+		}
+		mapValue := reflect.New(targetType.Elem()).Elem()
+		propertyValue := v.Get(k)
+		err := d.decode(propertyValue, mapValue, keyPath)
+		propertyValue.Free()
+		if err != nil {
+			return err
+		}
+		m.SetMapIndex(mapKey, mapValue)
+	}
+	return nil
+}
+
+// decodeElements decode the items of array, typed array or the values of Set to the slice or array
+func (d *decoder) decodeElements(v Value, target reflect.Value, path string) error {
+	if v.IsSet() {
+		index := 0
+		var err error
+		forEachErr := v.forEach(func(value, key Value) {
+			if err != nil || index >= target.Len() {
+				return
+			}
+			err = d.decode(value, target.Index(index), fmt.Sprintf("%s[%d]", path, index))
+			index++
+		})
+		if err == nil && forEachErr != nil {
+			err = &DecodeError{Path: path, Err: forEachErr}
+		}
+		return err
+	}
+	for index := 0; index < target.Len(); index++ {
+		item := v.GetByUint32(uint32(index))
+		err := d.decode(item, target.Index(index), fmt.Sprintf("%s[%d]", path, index))
+		item.Free()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elementsLen return the length of array, typed array or Set, false if v is not one of them
+func (v Value) elementsLen() (int, bool) {
+	switch {
+	case v.IsArray() || v.IsTypedArray():
+		return int(v.GetInt64("length")), true
+	case v.IsSet():
+		return int(v.size()), true
+	}
+	return 0, false
+}
+
+func (d *decoder) decodeSlice(v Value, target reflect.Value, path string) error {
+	targetType := target.Type()
+	if data, ok := v.bytes(); ok && targetType.Elem().Kind() == reflect.Uint8 {
+		target.Set(reflect.ValueOf(append([]byte(nil), data...)).Convert(targetType))
+		return nil
+	}
+	if v.IsTypedArray() {
+		if slice := reflect.ValueOf(v.typedArraySlice()); slice.Type().ConvertibleTo(targetType) {
+			target.Set(slice.Convert(targetType))
+			return nil
+		}
+	}
+
+	length, ok := v.elementsLen()
+	if !ok {
+		return d.mismatch(v, target, path)
+	}
+	key := decodeKey{ptr: C.GetValuePtr(v.ref), t: targetType}
+	if slice, ok := d.visited[key]; ok {
+		target.Set(slice)
+		return nil
+	}
+	if err := d.enter(); err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+	defer d.leave()
+	if err := d.reserve(int64(length)); err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+
+	slice := reflect.MakeSlice(targetType, length, length)
+	d.visited[key] = slice
+	target.Set(slice)
+	return d.decodeElements(v, slice, path)
+}
+
+func (d *decoder) decodeArray(v Value, target reflect.Value, path string) error {
+	if data, ok := v.bytes(); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+		if len(data) != target.Len() {
+			return d.errorf(path, "can not decode %d bytes to %s", len(data), target.Type())
+		}
+		reflect.Copy(target, reflect.ValueOf(data))
+		return nil
+	}
+	length, ok := v.elementsLen()
+	if !ok {
+		return d.mismatch(v, target, path)
+	}
+	if length != target.Len() && d.options.Strict {
+		return d.errorf(path, "can not decode %d items to %s", length, target.Type())
+	}
+	if err := d.enter(); err != nil {
+		return &DecodeError{Path: path, Err: err}
+	}
+	defer d.leave()
+
+	target.Set(reflect.Zero(target.Type()))
+	return d.decodeElements(v, target, path)
+}
+
+// decodeFunc wrap the javascript function as golang function, the arguments are converted by Context.Marshal,
+// the result is decoded to the first result type, and the javascript exception is returned as the error result,
+// the javascript function is referenced until the golang function is garbage collected or the Context is freed
+func (d *decoder) decodeFunc(v Value, target reflect.Value, path string) error {
+	if !v.IsFunction() {
+		return d.mismatch(v, target, path)
+	}
+	ref := d.ctx.reference(v.Dup())
+	fn, err := ref.reflectFunc(target.Type(), d.options)
+	if err != nil {
+		ref.release()
+		return &DecodeError{Path: path, Err: err}
+	}
+	target.Set(fn)
+	return nil
+}
+
+// reflectFunc wrap the javascript function as golang function of funcType, the arguments are converted by Context.Marshal,
+// the result is decoded to the first result type (the quickjs.Value result must be freed by the caller),
+// the javascript exception is returned as the trailing error result, or panic if there is no error result,
+// ErrContextFreed is returned (or panicked) once the Context is freed
+func (ref *jsReference) reflectFunc(funcType reflect.Type, options DecodeOptions) (reflect.Value, error) {
+	if funcType.Kind() != reflect.Func {
+		return reflect.Value{}, fmt.Errorf("%s is not a function type", funcType)
+	}
+	numOut := funcType.NumOut()
+	hasError := numOut > 0 && funcType.Out(numOut-1) == errorType
+	if numOut > 2 || (numOut == 2 && !hasError) {
+		return reflect.Value{}, fmt.Errorf("can not decode function to %s", funcType)
+	}
+	ctx := ref.ctx
+
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		defer stdruntime.KeepAlive(ref)
+		results := make([]reflect.Value, numOut)
+		for i := range results {
+			results[i] = reflect.New(funcType.Out(i)).Elem()
+		}
+		v, err := ref.value()
+
+		var jsArgs []Value
+		for _, arg := range args {
+			if err != nil {
+				break
+			}
+			var jsArg Value
+			if jsArg, err = ctx.Marshal(arg); err != nil {
+				break
+			}
+			if arg.Type() != valueType {
+				defer jsArg.Free()
+			}
+			jsArgs = append(jsArgs, jsArg)
+		}
+		result := ctx.Undefined()
+		if err == nil {
+			result = v.Call(jsArgs...)
+		}
+		defer result.Free()
+
+		switch {
+		case err != nil:
+		case result.IsException():
+			err = ctx.Exception()
+		case numOut == 0 || (hasError && numOut == 1):
+		case funcType.Out(0) == valueType:
+			results[0].Set(reflect.ValueOf(result.Dup()))
+		default:
+			err = result.DecodeWithOptions(results[0].Addr().Interface(), options)
+		}
+		if err != nil {
+			if !hasError {
+				panic(err)
+			}
+			results[numOut-1].Set(reflect.ValueOf(&err).Elem())
+		}
+		return results
+	}), nil
+}
@@ -0,0 +1,247 @@
+package quickjs
+
+import (
+	"errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"reflect"
+	stdruntime "runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+type DemoLevel int
+
+func (l *DemoLevel) UnmarshalJS(v Value) error {
+	switch v.String() {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level " + v.String())
+	}
+	return nil
+}
+
+type DemoDecoded struct {
+	Name     string
+	Count    int8
+	Ratio    float64
+	Big      *big.Int
+	At       time.Time
+	Data     []byte
+	Samples  []float32
+	Level    DemoLevel
+	Tags     map[string]int `mapstructure:"tags,omitempty"`
+	Callback func(int) (string, error)
+	Raw      Value
+	Extra    interface{}
+}
+
+func TestValue_DecodeTyped(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	v, err := ctx.EvalGlobal(`({
+	Name: "demo",
+	Count: 3,
+	Ratio: 0.5,
+	Big: 12345678901234567890n,
+	At: new Date(Date.UTC(2021, 0, 1)),
+	Data: new Uint8Array([1, 2]).buffer,
+	Samples: new Float32Array([1.5, 2.5]),
+	Level: "high",
+	Callback: (n) => { if (n < 0) { throw new Error("negative") } return "n=" + n },
+	Raw: { nested: true },
+	Extra: [1, "a"],
+})`)
+	require.NoError(t, err)
+	defer v.Free()
+
+	decoded := &DemoDecoded{}
+	require.NoError(t, v.Decode(decoded))
+	assert.Equal("demo", decoded.Name)
+	assert.Equal(int8(3), decoded.Count)
+	assert.Equal(0.5, decoded.Ratio)
+	assert.Equal("12345678901234567890", decoded.Big.String())
+	assert.True(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Equal(decoded.At))
+	assert.Equal([]byte{1, 2}, decoded.Data)
+	assert.Equal([]float32{1.5, 2.5}, decoded.Samples)
+	assert.Equal(DemoLevel(2), decoded.Level)
+	assert.Nil(decoded.Tags)
+	assert.True(decoded.Raw.GetByAtom(ctx.Atom("nested")).Bool())
+	decoded.Raw.Free()
+	assert.Equal([]interface{}{int64(1), "a"}, decoded.Extra)
+
+	s, err := decoded.Callback(2)
+	assert.NoError(err)
+	assert.Equal("n=2", s)
+	_, err = decoded.Callback(-1)
+	assert.EqualError(err, "Error: negative")
+
+	// the decoded functions are released once collected by golang
+	for i := 0; i < 100; i++ {
+		callback := &struct{ Callback func(int) (string, error) }{}
+		require.NoError(t, v.Decode(callback))
+	}
+	decoded = nil
+	assert.Equal(0, collectReferences(r, ctx))
+}
+
+func TestValue_DecodeErrors(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	decodeErr := func(code string, target interface{}, options DecodeOptions) *DecodeError {
+		v, err := ctx.EvalGlobal(code)
+		require.NoError(t, err)
+		defer v.Free()
+		err = v.DecodeWithOptions(target, options)
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			return nil
+		}
+		return decodeErr
+	}
+
+	err := decodeErr(`({ A: { B: "1" } })`, &DecodeStructBase{}, DecodeOptions{})
+	require.NotNil(t, err)
+	assert.Equal("A.B", err.Path)
+	assert.Equal("decode 'A.B': can not decode string to int", err.Error())
+
+	err = decodeErr(`({ Tags: { a: [1] } })`, &DemoDecoded{}, DecodeOptions{})
+	require.NotNil(t, err)
+	assert.Equal("Tags.a", err.Path)
+
+	err = decodeErr(`({ Level: "medium" })`, &DemoDecoded{}, DecodeOptions{})
+	require.NotNil(t, err)
+	assert.Equal("Level", err.Path)
+	assert.Equal("unknown level medium", errors.Unwrap(err).Error())
+
+	assert.Error(ctx.Undefined().Decode(DemoDecoded{}))
+
+	// fractions are truncated unless strict
+	counts := []int8{}
+	assert.Nil(decodeErr(`[1.5, -2.5]`, &counts, DecodeOptions{}))
+	assert.Equal([]int8{1, -2}, counts)
+	err = decodeErr(`[1, 1.5]`, &counts, DecodeOptions{Strict: true})
+	require.NotNil(t, err)
+	assert.Equal("[1]", err.Path)
+	assert.Contains(err.Error(), "without loss")
+	err = decodeErr(`[1, 300]`, &counts, DecodeOptions{})
+	require.NotNil(t, err)
+	assert.Equal("decode '[1]': 300 overflows int8", err.Error())
+	var small uint64
+	assert.NotNil(decodeErr(`-1`, &small, DecodeOptions{}))
+	assert.NotNil(decodeErr(`2n ** 64n`, &small, DecodeOptions{}))
+	assert.Nil(decodeErr(`2n ** 63n`, &small, DecodeOptions{Strict: true}))
+	assert.Equal(uint64(1)<<63, small)
+
+	// strict mode reject unknown keys and missing required fields
+	err = decodeErr(`({ a: { b: 1, c: 2 } })`, &DecodeStructBase{}, DecodeOptions{Strict: true})
+	require.NotNil(t, err)
+	assert.Equal("decode 'a': unknown keys 'c'", err.Error())
+	err = decodeErr(`({ Name: "demo" })`, &DemoSchedule{}, DecodeOptions{Strict: true})
+	require.NotNil(t, err)
+	assert.Equal("decode: missing required fields 'StartAt', 'Interval'", err.Error())
+	assert.Nil(decodeErr(`({ Name: "demo", StartAt: 0, Interval: "1s" })`, &DemoSchedule{}, DecodeOptions{Strict: true}))
+
+	// the inherited properties are missing
+	named := &struct {
+		Name string `json:"toString"`
+	}{}
+	err = decodeErr(`({})`, named, DecodeOptions{Strict: true})
+	require.NotNil(t, err)
+	assert.Equal("decode: missing required fields 'toString'", err.Error())
+	assert.Nil(decodeErr(`({ toString: "own" })`, named, DecodeOptions{Strict: true}))
+	assert.Equal("own", named.Name)
+
+	// the length of the huge sparse array is checked before the slice is allocated
+	ctx.SetConversionLimits(ConversionLimits{MaxElements: 100})
+	err = decodeErr(`[[1], Object.assign([], { length: 4294967295 })]`, &[][]int{}, DecodeOptions{})
+	require.NotNil(t, err)
+	assert.Equal("[1]", err.Path)
+	assert.Equal(ErrMaxElementsExceeded, errors.Unwrap(err))
+}
+
+func TestValue_DecodeHook(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	v, err := ctx.EvalGlobal(`({ Counts: { 1: "one" }, Tags: "a,b" })`)
+	require.NoError(t, err)
+	defer v.Free()
+
+	inventory := &DemoInventory{}
+	assert.Error(v.Decode(inventory))
+	require.NoError(t, v.DecodeWithOptions(inventory, DecodeOptions{DecodeHook: mapstructure.StringToSliceHookFunc(",")}))
+	assert.Equal(DemoInventory{Counts: map[int]string{1: "one"}, Tags: []string{"a", "b"}}, *inventory)
+
+	upper := func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if s, ok := data.(string); ok && to.Kind() == reflect.String {
+			return strings.ToUpper(s), nil
+		}
+		return data, nil
+	}
+	inventory = &DemoInventory{}
+	require.NoError(t, v.DecodeWithOptions(inventory, DecodeOptions{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(upper, mapstructure.StringToSliceHookFunc(",")),
+	}))
+	assert.Equal(DemoInventory{Counts: map[int]string{1: "ONE"}, Tags: []string{"A", "B"}}, *inventory)
+}
+
+func TestValue_DecodeArgumentsAndCycles(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	ctx.Globals().SetGoValue("sum", func(values []int) int {
+		total := 0
+		for _, value := range values {
+			total += value
+		}
+		return total
+	})
+	v, err := ctx.EvalGlobal(`sum([1, 2, 3])`)
+	require.NoError(t, err)
+	assert.Equal(int64(6), v.InterfaceAndFree())
+	_, err = ctx.EvalGlobal(`sum([1, "2"])`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "TypeError: argument 0: decode '[1]': can not decode string to int")
+
+	v, err = ctx.EvalGlobal(`var root = { Name: "root", Children: [] }; root.Next = root; root.Children.push(root); root`)
+	require.NoError(t, err)
+	defer v.Free()
+	root := &DemoNode{}
+	require.NoError(t, v.Decode(root))
+	assert.Equal("root", root.Name)
+	assert.True(root.Next.Next == root.Next)
+	assert.True(root.Children[0] == root.Next)
+}
@@ -0,0 +1,81 @@
+package quickjs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// decodeAs decode the value to T, the javascript exception is returned as error
+func decodeAs[T any](v Value) (T, error) {
+	var result T
+	if v.IsException() {
+		return result, v.ctx.Exception()
+	}
+	if _, ok := any(result).(Value); ok {
+		return any(v.Dup()).(T), nil
+	}
+	err := v.Decode(&result)
+	return result, err
+}
+
+// Get the property of v by the dot separated path and decode it to T, e.g. `Get[int](ctx.Globals(), "config.retries")`,
+// the quickjs.Value result must be freed by the caller
+func Get[T any](v Value, path string) (T, error) {
+	current := v.Dup()
+	defer func() { current.Free() }()
+	if len(path) > 0 {
+		for _, name := range strings.Split(path, ".") {
+			if current.IsUndefined() || current.IsNull() {
+				var result T
+				return result, fmt.Errorf("can not read property '%s' of '%s': %s", name, path, current.String())
+			}
+			next := current.Get(name)
+			current.Free()
+			current = next
+			if current.IsException() {
+				var result T
+				return result, v.ctx.Exception()
+			}
+		}
+	}
+	return decodeAs[T](current)
+}
+
+// Call the javascript function with golang arguments converted by Context.ToJSValue, and decode the result to T,
+// the quickjs.Value result must be freed by the caller
+func Call[T any](fn Value, args ...interface{}) (T, error) {
+	result := fn.DynamicCall(args...)
+	defer result.Free()
+	return decodeAs[T](result)
+}
+
+// EvalAs evaluate the code in global scope and decode the result to T,
+// the quickjs.Value result must be freed by the caller
+func EvalAs[T any](ctx *Context, code string) (T, error) {
+	result, err := ctx.EvalGlobal(code)
+	defer result.Free()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return decodeAs[T](result)
+}
+
+// BindFunc wrap the javascript function as golang function of type F, e.g. `BindFunc[func(int, int) (int, error)](add)`,
+// the arguments are converted by Context.Marshal and the result is decoded to the first result type,
+// the javascript exception is returned as the trailing error result, or panic if F has no error result,
+// fn is duplicated and released once the golang function is garbage collected or the Context is freed,
+// so the caller could free fn once bound, ErrContextFreed is returned (or panicked) after the Context is freed,
+// it panics if F is not a function type supported
+func BindFunc[F any](fn Value) F {
+	var f F
+	ref := fn.ctx.reference(fn.Dup())
+	reflectFunc, err := ref.reflectFunc(reflect.TypeOf(&f).Elem(), DecodeOptions{})
+	if err != nil {
+		ref.release()
+		panic(err)
+	}
+	reflect.ValueOf(&f).Elem().Set(reflectFunc)
+	return f
+}
@@ -0,0 +1,86 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+)
+
+func TestGenericHelpers(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	_, err := ctx.EvalGlobal(`
+var config = { retries: 3, hosts: ["a", "b"], server: { name: "demo" } };
+function add(a, b) { return a + b }
+function fail() { throw new Error("failed") }`)
+	require.NoError(t, err)
+
+	retries, err := Get[int](ctx.Globals(), "config.retries")
+	assert.NoError(err)
+	assert.Equal(3, retries)
+	host, err := Get[string](ctx.Globals(), "config.hosts.1")
+	assert.NoError(err)
+	assert.Equal("b", host)
+	server, err := Get[map[string]string](ctx.Globals(), "config.server")
+	assert.NoError(err)
+	assert.Equal(map[string]string{"name": "demo"}, server)
+	_, err = Get[string](ctx.Globals(), "config.missing.name")
+	assert.EqualError(err, "can not read property 'name' of 'config.missing.name': undefined")
+	_, err = Get[int](ctx.Globals(), "config.hosts")
+	assert.Error(err)
+
+	addFunc, err := Get[Value](ctx.Globals(), "add")
+	require.NoError(t, err)
+	defer addFunc.Free()
+	sum, err := Call[int](addFunc, 1, 2)
+	assert.NoError(err)
+	assert.Equal(3, sum)
+	text, err := Call[string](addFunc, "a", 1)
+	assert.NoError(err)
+	assert.Equal("a1", text)
+
+	failFunc, err := Get[Value](ctx.Globals(), "fail")
+	require.NoError(t, err)
+	defer failFunc.Free()
+	_, err = Call[int](failFunc)
+	assert.EqualError(err, "Error: failed")
+
+	hosts, err := EvalAs[[]string](ctx, `config.hosts.map(h => h.toUpperCase())`)
+	assert.NoError(err)
+	assert.Equal([]string{"A", "B"}, hosts)
+	_, err = EvalAs[int](ctx, `throw new TypeError("bad")`)
+	assert.EqualError(err, "TypeError: bad")
+
+	add := BindFunc[func(int, int) int](addFunc)
+	assert.Equal(5, add(2, 3))
+	// the bound function is kept until it is collected by golang
+	sumFunc, err := Get[Value](ctx.Globals(), "add")
+	require.NoError(t, err)
+	sum2 := BindFunc[func(int, int) int](sumFunc)
+	sumFunc.Free()
+	assert.Equal(7, sum2(3, 4))
+	for i := 0; i < 100; i++ {
+		BindFunc[func(int, int) int](addFunc)
+	}
+	add, sum2 = nil, nil
+	assert.Equal(0, collectReferences(r, ctx))
+	mustFail := BindFunc[func() (int, error)](failFunc)
+	_, err = mustFail()
+	assert.EqualError(err, "Error: failed")
+	concat := BindFunc[func(string, int) (Value, error)](addFunc)
+	result, err := concat("x", 1)
+	assert.NoError(err)
+	assert.Equal("x1", result.String())
+	result.Free()
+
+	assert.Panics(func() { BindFunc[int](addFunc) })
+	assert.Panics(func() { BindFunc[func() (int, int)](addFunc) })
+}
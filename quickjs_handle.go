@@ -0,0 +1,69 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// golang values referenced by javascript objects, the id is stored as opaque of the object
+var handleLen int64
+var handleLock sync.Mutex
+var handleStore = make(map[int64]interface{})
+var goValueClassID C.JSClassID
+
+func init() { C.JS_NewClassID(&goValueClassID) }
+
+func storeHandle(v interface{}) int64 {
+	id := atomic.AddInt64(&handleLen, 1)
+	handleLock.Lock()
+	defer handleLock.Unlock()
+	handleStore[id] = v
+	return id
+}
+
+func restoreHandle(id int64) (interface{}, bool) {
+	handleLock.Lock()
+	defer handleLock.Unlock()
+	v, ok := handleStore[id]
+	return v, ok
+}
+
+func freeHandle(id int64) {
+	handleLock.Lock()
+	defer handleLock.Unlock()
+	delete(handleStore, id)
+}
+
+//export goValueFinalizer
+func goValueFinalizer(rt *C.JSRuntime, val C.JSValue) {
+	freeHandle(int64(C.GetOpaqueID(val, goValueClassID)))
+}
+
+// goValue wrap golang value into an opaque javascript object,
+// the golang value is released once the object is finalized
+func (ctx *Context) goValue(v interface{}) Value {
+	val := ctx.newValue(C.JS_NewObjectClass(ctx.ref, C.int(goValueClassID)))
+	if val.IsException() {
+		return val
+	}
+	C.SetOpaqueID(val.ref, C.int64_t(storeHandle(v)))
+	return val
+}
+
+// goValue unwrap the golang value from object created by Context.goValue
+func (v Value) goValue() (interface{}, bool) {
+	id := int64(C.GetOpaqueID(v.ref, goValueClassID))
+	if id == 0 {
+		return nil, false
+	}
+	return restoreHandle(id)
+}
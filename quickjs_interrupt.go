@@ -0,0 +1,109 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// InterruptedError is returned when an evaluation is aborted because its context.Context is done,
+// it wraps context.Canceled or context.DeadlineExceeded
+type InterruptedError struct {
+	Err   error
+	Stack string
+}
+
+func (err *InterruptedError) Error() string { return "interrupted: " + err.Err.Error() }
+
+func (err *InterruptedError) Unwrap() error { return err.Err }
+
+// interruptScope is the context.Context watched by the interrupt handler of a Runtime
+type interruptScope struct {
+	ctx context.Context
+	err error
+}
+
+//export interruptHandler
+func interruptHandler(rt *C.JSRuntime, opaque unsafe.Pointer) C.int {
+	// the handler is polled frequently, so the state is passed as opaque instead of the locked runtimeStore
+	state := cgo.Handle(uintptr(opaque)).Value().(*runtimeState)
+	if state.interrupt == nil {
+		return 0
+	}
+	if err := state.interrupt.ctx.Err(); err != nil {
+		state.interrupt.err = err
+		return 1
+	}
+	return 0
+}
+
+// withInterrupt run fn and abort the javascript execution once goCtx is done,
+// the previous scope is restored after fn return, so that the calls could be nested
+func (ctx *Context) withInterrupt(goCtx context.Context, fn func() Value) (Value, error) {
+	if err := goCtx.Err(); err != nil {
+		return ctx.Undefined(), &InterruptedError{Err: err}
+	}
+
+	r := ctx.runtime
+	scope := &interruptScope{ctx: goCtx}
+	prev := r.state.interrupt
+	r.state.interrupt = scope
+	if prev == nil {
+		handle := cgo.NewHandle(r.state)
+		C.SetInterruptHandler(r.ref, C.uintptr_t(handle))
+		defer func() {
+			C.ClearInterruptHandler(r.ref)
+			handle.Delete()
+		}()
+	}
+	defer func() { r.state.interrupt = prev }()
+
+	val := fn()
+	if !val.IsException() {
+		return val, nil
+	}
+
+	err := ctx.Exception()
+	if scope.err == nil {
+		return val, err
+	}
+
+	interrupted := &InterruptedError{Err: scope.err}
+	var jsErr *Error
+	if errors.As(err, &jsErr) {
+		interrupted.Stack = jsErr.Stack
+	}
+	return val, interrupted
+}
+
+// EvalGlobalWithContext evaluate code in global scope, abort it once goCtx is done
+func (ctx *Context) EvalGlobalWithContext(goCtx context.Context, code string) (Value, error) {
+	return ctx.EvalFileWithContext(goCtx, code, "code", 0)
+}
+
+// EvalModuleWithContext evaluate code as module, abort it once goCtx is done
+func (ctx *Context) EvalModuleWithContext(goCtx context.Context, code string) (Value, error) {
+	return ctx.EvalFileWithContext(goCtx, code, "code", 1)
+}
+
+// EvalFileWithContext same as EvalFile, but abort the evaluation once goCtx is cancelled or its deadline passed,
+// the returned error will be an *InterruptedError in that case
+func (ctx *Context) EvalFileWithContext(goCtx context.Context, code, filename string, mod int) (Value, error) {
+	return ctx.withInterrupt(goCtx, func() Value { return ctx.evalFile(code, filename, mod) })
+}
+
+// CallWithGoContext call function with this parameter, abort it once goCtx is cancelled or its deadline passed,
+// the returned error will be an *InterruptedError in that case
+func (v Value) CallWithGoContext(goCtx context.Context, thisArg Value, args ...Value) (Value, error) {
+	return v.ctx.withInterrupt(goCtx, func() Value { return v.CallWithContext(thisArg, args...) })
+}
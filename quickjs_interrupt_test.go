@@ -0,0 +1,95 @@
+package quickjs
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	stdruntime "runtime"
+	"testing"
+	"time"
+)
+
+func TestContext_EvalGlobalWithContextTimeout(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	goCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := ctx.EvalGlobalWithContext(goCtx, `while(true){}`)
+	assert.Error(err)
+	var interrupted *InterruptedError
+	assert.True(errors.As(err, &interrupted))
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+
+	// runtime is still usable after interrupted
+	v, err := ctx.EvalGlobal(`1 + 1`)
+	assert.Nil(err)
+	defer v.Free()
+	assert.Equal(int64(2), v.Int64())
+}
+
+func TestContext_EvalGlobalWithContextCancel(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	goCtx, cancel := context.WithCancel(context.Background())
+	ctx.Globals().SetFunction("cancel", func(ctx *Context, this Value, args []Value) Value {
+		cancel()
+		return ctx.Undefined()
+	})
+
+	_, err := ctx.EvalGlobalWithContext(goCtx, `cancel(); try { while(true){} } catch (e) {}`)
+	assert.True(errors.Is(err, context.Canceled))
+
+	_, err = ctx.EvalGlobalWithContext(goCtx, `1`)
+	assert.True(errors.Is(err, context.Canceled))
+
+	v, err := ctx.EvalGlobalWithContext(context.Background(), `"done"`)
+	assert.Nil(err)
+	defer v.Free()
+	assert.Equal("done", v.String())
+
+	_, err = ctx.EvalGlobalWithContext(context.Background(), `throw new Error("failed")`)
+	var interrupted *InterruptedError
+	assert.False(errors.As(err, &interrupted))
+	assert.Equal("Error: failed", err.Error())
+}
+
+func TestValue_CallWithGoContext(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	_, err := ctx.EvalGlobal(`function loop(n) { while(n) {} return 42 }`)
+	assert.Nil(err)
+	loop := ctx.Globals().Get("loop")
+	defer loop.Free()
+
+	goCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = loop.CallWithGoContext(goCtx, ctx.Null(), ctx.Bool(true))
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+
+	result, err := loop.CallWithGoContext(context.Background(), ctx.Null(), ctx.Bool(false))
+	assert.Nil(err)
+	defer result.Free()
+	assert.Equal(int64(42), result.Int64())
+}
@@ -0,0 +1,72 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import "unsafe"
+
+// the builtin functions used by the conversions, e.g. `Map.prototype.set`
+const (
+	intrinsicMap = iota
+	intrinsicMapSet
+	intrinsicMapForEach
+	intrinsicMapSize
+	intrinsicSetForEach
+	intrinsicSetSize
+	intrinsicSymbol
+	intrinsicSymbolFor
+	intrinsicSymbolKeyFor
+	intrinsicSymbolDescription
+)
+
+// intrinsicsSource is the script returning the builtin functions in the order of the constants above,
+// the getters are returned for the accessor properties like `Map.prototype.size`
+const intrinsicsSource = `(() => {
+	const getter = (proto, name) => Object.getOwnPropertyDescriptor(proto, name).get;
+	return [
+		Map, Map.prototype.set, Map.prototype.forEach, getter(Map.prototype, "size"),
+		Set.prototype.forEach, getter(Set.prototype, "size"),
+		Symbol, Symbol.for, Symbol.keyFor, getter(Symbol.prototype, "description"),
+	];
+})()`
+
+// privateAtom create the atom of a private symbol, the properties keyed by it are invisible to javascript
+func (ctx *Context) privateAtom(description string) C.JSAtom {
+	descriptionPtr := C.CString(description)
+	defer C.free(unsafe.Pointer(descriptionPtr))
+	key := ctx.newValue(C.JS_NewPrivateSymbol(ctx.ref, descriptionPtr))
+	defer key.Free()
+	return C.JS_ValueToAtom(ctx.ref, key.ref)
+}
+
+// captureIntrinsics keep the builtin functions before any script is evaluated, so that the conversions
+// are not affected by the globals overwritten by script, they are kept by a private property of the global object
+func (ctx *Context) captureIntrinsics() {
+	ctx.intrinsicsKey = ctx.privateAtom("quickjs.intrinsics")
+	intrinsics := ctx.eval(intrinsicsSource)
+	global := ctx.newValue(C.JS_GetGlobalObject(ctx.ref))
+	defer global.Free()
+	C.JS_DefinePropertyValue(ctx.ref, global.ref, ctx.intrinsicsKey, intrinsics.transfer(), 0)
+}
+
+// intrinsic return the builtin function captured when the context is created, it must be freed by the caller
+func (ctx *Context) intrinsic(index int) Value {
+	global := ctx.newValue(C.JS_GetGlobalObject(ctx.ref))
+	defer global.Free()
+	intrinsics := ctx.newValue(C.JS_GetProperty(ctx.ref, global.ref, ctx.intrinsicsKey))
+	defer intrinsics.Free()
+	return intrinsics.GetByUint32(uint32(index))
+}
+
+// callIntrinsic call the builtin function with this and args
+func (ctx *Context) callIntrinsic(index int, this Value, args ...Value) Value {
+	fn := ctx.intrinsic(index)
+	defer fn.Free()
+	return fn.CallWithContext(this, args...)
+}
@@ -0,0 +1,206 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"reflect"
+	stdruntime "runtime"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// LeakedObject is a javascript object which is still referenced outside of javascript
+type LeakedObject struct {
+	// Type of the GC object, e.g. object, function_bytecode, context
+	Type string
+	// Class name of the object, e.g. Object, Array, Function
+	Class string
+	// RefCount is the number of the external references
+	RefCount int
+	// Stack of the golang call which created the latest Value not freed,
+	// it is empty if the object is not referenced by the Values of the bindings
+	Stack string
+}
+
+// LeakSite group the Values not freed by the golang call stack which created them
+type LeakSite struct {
+	// Caller is the first frame of the stack outside of the bindings, e.g. `main.run (/src/main.go:12)`
+	Caller string
+	Stack  string
+	Count  int
+}
+
+// LeakReport of the javascript objects referenced outside of javascript
+type LeakReport struct {
+	Objects []LeakedObject
+	// Sites of the Values not freed, the most leaking first
+	Sites []LeakSite
+}
+
+// Leaked report whether there is any object not released
+func (r LeakReport) Leaked() bool { return len(r.Objects) > 0 }
+
+func (r LeakReport) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d objects leaked", len(r.Objects))
+	for _, site := range r.Sites {
+		fmt.Fprintf(b, "\n\n%d values created at %s\n%s", site.Count, site.Caller, site.Stack)
+	}
+	return b.String()
+}
+
+// TrackLeaks record the golang call stack whenever a javascript object is returned by the bindings,
+// so that the Values not freed can be located by Runtime.LeakReport, it is for debugging only,
+// onLeak is called by Runtime.Free with the report instead of aborting the process if any object
+// is leaked, the runtime is not released then, so that the leaked values are still valid,
+// use Runtime.ForceFree to release it, Runtime.Free panics with the report if onLeak is nil
+func (r Runtime) TrackLeaks(onLeak func(report LeakReport)) {
+	if r.state.leaks == nil {
+		r.state.leaks = &leakTracker{records: make(map[unsafe.Pointer][]leakRecord)}
+	}
+	r.state.leaks.onLeak = onLeak
+}
+
+// LeakReport of the objects referenced outside of javascript, includes the contexts and the values
+// not freed yet, it runs the garbage collection, the stacks are empty unless Runtime.TrackLeaks
+func (r Runtime) LeakReport() LeakReport {
+	count := int(C.JS_GetExternalRefs(r.ref, nil, 0))
+	if count == 0 {
+		return LeakReport{}
+	}
+	refs := make([]C.JSExternalRef, count)
+	count = int(C.JS_GetExternalRefs(r.ref, &refs[0], C.int(count)))
+	if count < len(refs) {
+		refs = refs[:count]
+	}
+
+	report := LeakReport{Objects: make([]LeakedObject, 0, len(refs))}
+	sites := make(map[string]*LeakSite)
+	for _, ref := range refs {
+		object := LeakedObject{
+			Type:     gcObjectTypes[int(ref.gc_obj_type)],
+			Class:    C.GoString(&ref.class_name[0]),
+			RefCount: int(ref.ref_count),
+		}
+		if r.state.leaks != nil {
+			for _, record := range r.state.leaks.records[ref.ptr] {
+				caller, stack := record.format()
+				object.Stack = stack
+				site, ok := sites[stack]
+				if !ok {
+					site = &LeakSite{Caller: caller, Stack: stack}
+					sites[stack] = site
+				}
+				site.Count++
+			}
+		}
+		report.Objects = append(report.Objects, object)
+	}
+	for _, site := range sites {
+		report.Sites = append(report.Sites, *site)
+	}
+	sort.Slice(report.Sites, func(i, j int) bool {
+		if report.Sites[i].Count != report.Sites[j].Count {
+			return report.Sites[i].Count > report.Sites[j].Count
+		}
+		return report.Sites[i].Stack < report.Sites[j].Stack
+	})
+	return report
+}
+
+// gcObjectTypes is the JSGCObjectTypeEnum of quickjs
+var gcObjectTypes = map[int]string{
+	0: "object",
+	1: "function_bytecode",
+	2: "shape",
+	3: "var_ref",
+	4: "async_function",
+	5: "context",
+}
+
+// leakTracker record the creation stacks of the references owned by golang
+type leakTracker struct {
+	onLeak func(report LeakReport)
+	// the records of a GC object, the latest is freed first
+	records map[unsafe.Pointer][]leakRecord
+}
+
+type leakRecord []uintptr
+
+func (t *leakTracker) track(ptr unsafe.Pointer, skip int) {
+	pcs := make([]uintptr, 32)
+	n := stdruntime.Callers(skip+1, pcs)
+	t.records[ptr] = append(t.records[ptr], leakRecord(pcs[:n]))
+}
+
+func (t *leakTracker) untrack(ptr unsafe.Pointer) {
+	records := t.records[ptr]
+	switch len(records) {
+	case 0:
+	case 1:
+		delete(t.records, ptr)
+	default:
+		t.records[ptr] = records[:len(records)-1]
+	}
+}
+
+// bindingsPkg is the import path of the bindings, the frames of it are skipped for the caller
+var bindingsPkg = reflect.TypeOf(leakTracker{}).PkgPath() + "."
+
+// format the record as the caller outside of the bindings and the full stack
+func (record leakRecord) format() (caller string, stack string) {
+	b := &strings.Builder{}
+	frames := stdruntime.CallersFrames(record)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if len(caller) == 0 && (!strings.HasPrefix(frame.Function, bindingsPkg) || strings.HasSuffix(frame.File, "_test.go")) {
+			caller = fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return caller, b.String()
+}
+
+// leaks of the runtime, nil unless Runtime.TrackLeaks
+func (ctx *Context) leaks() *leakTracker {
+	if ctx.runtime == nil || ctx.runtime.state == nil {
+		return nil
+	}
+	return ctx.runtime.state.leaks
+}
+
+// track the reference owned by v, skip is the number of the callers of track to skip
+func (v Value) track(skip int) {
+	if t := v.ctx.leaks(); t != nil && v.isGCObject() {
+		t.track(unsafe.Pointer(C.GetValuePtr(v.ref)), skip+1)
+	}
+}
+
+func (v Value) untrack() {
+	if t := v.ctx.leaks(); t != nil && v.isGCObject() {
+		t.untrack(unsafe.Pointer(C.GetValuePtr(v.ref)))
+	}
+}
+
+func (v Value) isGCObject() bool {
+	tag := v.getTag()
+	return tag == JsTagOBJECT || tag == JsTagFunctionByteCode
+}
+
+// transfer the reference owned by v to the quickjs function which frees it, e.g. JS_SetProperty
+func (v Value) transfer() C.JSValue {
+	v.untrack()
+	return v.ref
+}
@@ -0,0 +1,79 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+)
+
+func TestRuntime_TrackLeaks(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	var reports []LeakReport
+	r := NewRuntime()
+	r.TrackLeaks(func(report LeakReport) { reports = append(reports, report) })
+	ctx := r.NewContext()
+
+	leaked, err := ctx.EvalGlobal(`({ leaked: true })`)
+	require.NoError(t, err)
+	freed := ctx.Array()
+	ctx.Globals().Set("kept", ctx.Object())
+	freed.Free()
+
+	report := r.LeakReport()
+	types := []string{}
+	for _, object := range report.Objects {
+		types = append(types, object.Type)
+	}
+	// the global object is owned by the context until it is freed
+	assert.ElementsMatch([]string{"context", "object", "object"}, types)
+	require.Len(t, report.Sites, 3)
+	for _, site := range report.Sites {
+		assert.Contains(site.Caller, "TestRuntime_TrackLeaks")
+	}
+
+	ctx.Free()
+	r.Free()
+	require.Len(t, reports, 1)
+	report = reports[0]
+	assert.True(report.Leaked())
+	require.Len(t, report.Objects, 1)
+	assert.Equal(LeakedObject{Type: "object", Class: "Object", RefCount: 1, Stack: report.Sites[0].Stack}, report.Objects[0])
+	require.Len(t, report.Sites, 1)
+	assert.Equal(1, report.Sites[0].Count)
+	assert.Contains(report.Sites[0].Caller, "TestRuntime_TrackLeaks")
+	assert.Contains(report.Sites[0].Stack, "(*Context).EvalGlobal")
+	assert.Contains(report.String(), "1 objects leaked")
+	assert.True(leaked.IsObject())
+
+	// the leaking runtime is released by force
+	leakingRef := r.ref
+	r.ForceFree()
+	assert.Nil(restoreRuntimeState(leakingRef))
+
+	// the report is panicked without callback
+	r = NewRuntime()
+	r.TrackLeaks(nil)
+	ctx = r.NewContext()
+	_, err = ctx.EvalGlobal(`({})`)
+	require.NoError(t, err)
+	ctx.Free()
+	assert.Panics(r.Free)
+	r.ForceFree()
+
+	// the runtime is released if nothing is leaked
+	r = NewRuntime()
+	r.TrackLeaks(func(report LeakReport) { reports = append(reports, report) })
+	ctx = r.NewContext()
+	v, err := ctx.EvalGlobal(`var values = [{}, {}]; values`)
+	require.NoError(t, err)
+	assert.True(r.LeakReport().Leaked())
+	v.Free()
+	ctx.Free()
+	assert.False(r.LeakReport().Leaked())
+	r.Free()
+	assert.Len(reports, 1)
+}
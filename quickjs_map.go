@@ -0,0 +1,270 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// MapEntry is the entry of javascript Map
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// OrderedMap is the golang representation of javascript Map, the entries are kept in insertion order,
+// and the keys could be any values (including objects) as javascript does
+type OrderedMap []MapEntry
+
+var orderedMapType = reflect.TypeOf(OrderedMap{})
+
+// Get the value of key, only comparable keys could be found
+func (m OrderedMap) Get(key interface{}) (interface{}, bool) {
+	if key != nil && !reflect.TypeOf(key).Comparable() {
+		return nil, false
+	}
+	for _, entry := range m {
+		if entry.Key != nil && !reflect.TypeOf(entry.Key).Comparable() {
+			continue
+		}
+		if entry.Key == key {
+			return entry.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Keys of the map in insertion order
+func (m OrderedMap) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for _, entry := range m {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+// SetGoMapAsJSMap convert golang maps to javascript Map instead of object in Context.ToJSValue,
+// the keys of javascript Map keep their types, e.g. map[int]string{1: "a"} will be `new Map([[1, "a"]])`
+func (ctx *Context) SetGoMapAsJSMap(enabled bool) { ctx.goMapAsJSMap = enabled }
+
+// Map create javascript Map with the entries
+func (ctx *Context) Map(m OrderedMap) Value {
+	val, err := ctx.newJSConverter().jsMap(m)
+	if err != nil {
+		return ctx.ThrowRangeError("%v", err)
+	}
+	return val
+}
+
+func (c *jsConverter) jsMap(m OrderedMap) (Value, error) {
+	ctx := c.ctx
+	if err := c.enter(); err != nil {
+		return ctx.Undefined(), err
+	}
+	defer c.leave()
+
+	mapCtor := ctx.intrinsic(intrinsicMap)
+	defer mapCtor.Free()
+	val := mapCtor.New()
+	if val.IsException() {
+		return val, ctx.Exception()
+	}
+	for _, entry := range m {
+		if err := c.setMapEntry(val, entry.Key, entry.Value); err != nil {
+			val.Free()
+			return ctx.Undefined(), err
+		}
+	}
+	return val, nil
+}
+
+// setMapEntry invoke the builtin `Map.prototype.set` with the converted key and value
+func (c *jsConverter) setMapEntry(m Value, key interface{}, value interface{}) error {
+	jsKey, err := c.toJS(key)
+	if err != nil {
+		return err
+	}
+	defer jsKey.Free()
+	jsValue, err := c.toJS(value)
+	if err != nil {
+		return err
+	}
+	defer jsValue.Free()
+
+	result := c.ctx.callIntrinsic(intrinsicMapSet, m, jsKey, jsValue)
+	defer result.Free()
+	if result.IsException() {
+		return c.ctx.Exception()
+	}
+	return nil
+}
+
+// goMapToJSMap convert the golang map to javascript Map
+func (c *jsConverter) goMapToJSMap(key visitKey, reflectValue reflect.Value) (Value, error) {
+	ctx := c.ctx
+	if err := c.enter(); err != nil {
+		return ctx.Undefined(), err
+	}
+	defer c.leave()
+
+	mapCtor := ctx.intrinsic(intrinsicMap)
+	defer mapCtor.Free()
+	val := mapCtor.New()
+	if val.IsException() {
+		return val, ctx.Exception()
+	}
+	c.visited[key] = val
+	for _, mapKey := range reflectValue.MapKeys() {
+		if err := c.setMapEntry(val, mapKey.Interface(), reflectValue.MapIndex(mapKey).Interface()); err != nil {
+			val.Free()
+			return ctx.Undefined(), err
+		}
+	}
+	return val, nil
+}
+
+// goMapToObject convert the golang map to javascript object, the keys are converted to property keys,
+// so that Symbol keys define symbol properties and number keys become strings
+func (c *jsConverter) goMapToObject(key visitKey, reflectValue reflect.Value) (Value, error) {
+	ctx := c.ctx
+	if err := c.enter(); err != nil {
+		return ctx.Undefined(), err
+	}
+	defer c.leave()
+
+	obj := ctx.Object()
+	c.visited[key] = obj
+	for _, mapKey := range reflectValue.MapKeys() {
+		jsKey, err := c.toJS(mapKey.Interface())
+		if err != nil {
+			obj.Free()
+			return ctx.Undefined(), err
+		}
+		keyAtom := Atom{ctx: ctx, ref: C.JS_ValueToAtom(ctx.ref, jsKey.ref)}
+		jsKey.Free()
+		jsValue, err := c.toJS(reflectValue.MapIndex(mapKey).Interface())
+		if err != nil {
+			keyAtom.Free()
+			obj.Free()
+			return ctx.Undefined(), err
+		}
+		obj.SetByAtom(keyAtom, jsValue)
+		keyAtom.Free()
+	}
+	return obj, nil
+}
+
+// forEach invoke the builtin `forEach` method of Map or Set with golang callback
+func (v Value) forEach(fn func(value, key Value)) error {
+	forEach := intrinsicSetForEach
+	if v.IsMap() {
+		forEach = intrinsicMapForEach
+	}
+	callback := v.ctx.Function(func(ctx *Context, this Value, args []Value) Value {
+		value, key := ctx.Undefined(), ctx.Undefined()
+		if len(args) > 1 {
+			value, key = args[0], args[1]
+		}
+		fn(value, key)
+		return ctx.Undefined()
+	})
+	defer callback.Free()
+	result := v.ctx.callIntrinsic(forEach, v, callback)
+	defer result.Free()
+	if result.IsException() {
+		return v.ctx.Exception()
+	}
+	return nil
+}
+
+// size of Map or Set by the builtin getter of `size`
+func (v Value) size() int64 {
+	getter := intrinsicSetSize
+	if v.IsMap() {
+		getter = intrinsicMapSize
+	}
+	size := v.ctx.callIntrinsic(getter, v)
+	defer size.Free()
+	return size.Int64()
+}
+
+// OrderedMap return the entries of javascript Map
+func (v Value) OrderedMap() OrderedMap {
+	m, _ := v.Export()
+	if m, ok := m.(OrderedMap); ok {
+		return m
+	}
+	return nil
+}
+
+func (c *goConverter) jsMapToOrderedMap(ptr unsafe.Pointer, v Value) (interface{}, error) {
+	size := v.size()
+	if err := c.reserve(size); err != nil {
+		return nil, err
+	}
+	m := make(OrderedMap, size)
+	c.visited[ptr] = m
+	index := 0
+	var err error
+	forEachErr := v.forEach(func(value, key Value) {
+		if err != nil || index >= len(m) {
+			return
+		}
+		if m[index].Key, err = c.toGo(key); err != nil {
+			return
+		}
+		m[index].Value, err = c.toGo(value)
+		index++
+	})
+	if err == nil {
+		err = forEachErr
+	}
+	return m[:index], err
+}
+
+// jsSetToSlice return the values of javascript Set
+func (c *goConverter) jsSetToSlice(ptr unsafe.Pointer, v Value) (interface{}, error) {
+	size := v.size()
+	if err := c.reserve(size); err != nil {
+		return nil, err
+	}
+	s := make([]interface{}, size)
+	c.visited[ptr] = s
+	index := 0
+	var err error
+	forEachErr := v.forEach(func(value, key Value) {
+		if err != nil || index >= len(s) {
+			return
+		}
+		s[index], err = c.toGo(value)
+		index++
+	})
+	if err == nil {
+		err = forEachErr
+	}
+	return s[:index], err
+}
+
+// mapDecodeHook convert OrderedMap to golang map for Value.Decode, so that it could be decoded to map[K]V or struct
+func mapDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from != orderedMapType || (to.Kind() != reflect.Map && to.Kind() != reflect.Struct) {
+		return data, nil
+	}
+	m := make(map[interface{}]interface{}, len(data.(OrderedMap)))
+	for _, entry := range data.(OrderedMap) {
+		if entry.Key != nil && !reflect.TypeOf(entry.Key).Comparable() {
+			return nil, fmt.Errorf("can not use '%T' as map key", entry.Key)
+		}
+		m[entry.Key] = entry.Value
+	}
+	return m, nil
+}
@@ -0,0 +1,176 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+)
+
+type DemoInventory struct {
+	Counts map[int]string
+	Tags   []string
+}
+
+func TestValue_MapAndSet(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	v, err := ctx.EvalGlobal(`new Map([["b", 1], [2, "two"], [true, null], [{ a: 1 }, [1]]])`)
+	require.NoError(t, err)
+	assert.True(v.IsMap())
+	m := v.InterfaceAndFree().(OrderedMap)
+	assert.Equal(OrderedMap{
+		{Key: "b", Value: int64(1)},
+		{Key: int64(2), Value: "two"},
+		{Key: true, Value: nil},
+		{Key: map[string]interface{}{"a": int64(1)}, Value: []interface{}{int64(1)}},
+	}, m)
+	assert.Equal([]interface{}{"b", int64(2), true, map[string]interface{}{"a": int64(1)}}, m.Keys())
+	two, ok := m.Get(int64(2))
+	assert.True(ok)
+	assert.Equal("two", two)
+	_, ok = m.Get(map[string]interface{}{})
+	assert.False(ok)
+
+	v, err = ctx.EvalGlobal(`new Set([3, "a", 3, 1])`)
+	require.NoError(t, err)
+	assert.True(v.IsSet())
+	assert.Equal([]interface{}{int64(3), "a", int64(1)}, v.InterfaceAndFree())
+
+	v, err = ctx.EvalGlobal(`({ Counts: new Map([[1, "one"], [2, "two"]]), Tags: new Set(["x", "y"]) })`)
+	require.NoError(t, err)
+	inventory := &DemoInventory{}
+	assert.NoError(v.Decode(inventory))
+	v.Free()
+	assert.Equal(DemoInventory{Counts: map[int]string{1: "one", 2: "two"}, Tags: []string{"x", "y"}}, *inventory)
+
+	ctx.Globals().SetGoValue("count", func(counts map[int]string) int { return len(counts) })
+	v, err = ctx.EvalGlobal(`count(new Map([[1, "a"], [2, "b"], [3, "c"]]))`)
+	require.NoError(t, err)
+	assert.Equal(int64(3), v.InterfaceAndFree())
+
+	jsMap := ctx.ToJSValue(OrderedMap{{Key: 2, Value: "b"}, {Key: "1", Value: "a"}})
+	ctx.Globals().Set("jsMap", jsMap)
+	v, err = ctx.EvalGlobal(`[jsMap instanceof Map, [...jsMap.keys()].map(k => typeof k).join()]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{true, "number,string"}, v.InterfaceAndFree())
+
+	// the builtin methods are used regardless of the properties overwritten by script
+	v, err = ctx.EvalGlobal(`
+globalThis.Map = function() { return {} };
+Object.defineProperty(Set.prototype, "size", { get() { return 4294967295 } });
+Set.prototype.forEach = () => {};
+Object.defineProperty(new Set(["s"]), "size", { value: 4294967295 })`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"s"}, v.InterfaceAndFree())
+	jsMap = ctx.ToJSValue(OrderedMap{{Key: "a", Value: 1}})
+	assert.True(jsMap.IsMap())
+	jsMap.Free()
+
+	// the size is checked before the map or slice is allocated
+	ctx.SetConversionLimits(ConversionLimits{MaxElements: 100})
+	v, err = ctx.EvalGlobal(`new Set(Array.from({ length: 200 }, (_, i) => i))`)
+	require.NoError(t, err)
+	_, err = v.Export()
+	assert.Equal(ErrMaxElementsExceeded, err)
+	v.Free()
+}
+
+func TestContext_SetGoMapAsJSMap(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	ctx.Globals().SetGoValue("object", map[int]string{1: "a"})
+	ctx.SetGoMapAsJSMap(true)
+	ctx.Globals().SetGoValue("jsMap", map[int]string{1: "a"})
+
+	v, err := ctx.EvalGlobal(`[object instanceof Map, object["1"], jsMap instanceof Map, jsMap.get(1)]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{false, "a", true, "a"}, v.InterfaceAndFree())
+}
+
+func TestContext_Symbol(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	ctx.Globals().Set("first", ctx.Symbol("id"))
+	ctx.Globals().Set("second", ctx.Symbol("id"))
+	ctx.Globals().SetGoValue("registered", Symbol{Description: "app", Registered: true})
+	ctx.Globals().SetGoValue("iterator", SymbolIterator)
+
+	v, err := ctx.EvalGlobal(`[typeof first, first === second, registered === Symbol.for("app"), iterator === Symbol.iterator]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"symbol", false, true, true}, v.InterfaceAndFree())
+
+	v, err = ctx.EvalGlobal(`[first, Symbol.for("app"), Symbol.iterator, Symbol()]`)
+	require.NoError(t, err)
+	symbols := v.InterfaceAndFree().([]interface{})
+	assert.Equal([]interface{}{
+		Symbol{Description: "id"},
+		Symbol{Description: "app", Registered: true},
+		SymbolIterator,
+		Symbol{},
+	}, symbols)
+	assert.True(symbols[2].(Symbol).IsWellKnown())
+	assert.Equal("Symbol(id)", symbols[0].(Symbol).String())
+
+	// the unique symbols are not well-known even if they have the same description
+	v, err = ctx.EvalGlobal(`Symbol("Symbol.iterator")`)
+	require.NoError(t, err)
+	fake := v.InterfaceAndFree().(Symbol)
+	assert.False(fake.IsWellKnown())
+	assert.NotEqual(SymbolIterator, fake)
+	ctx.Globals().SetGoValue("fake", fake)
+	v, err = ctx.EvalGlobal(`fake !== Symbol.iterator && fake.description === "Symbol.iterator"`)
+	require.NoError(t, err)
+	assert.Equal(true, v.InterfaceAndFree())
+
+	// define symbol keyed properties from golang
+	ctx.Globals().SetGoValue("range", map[interface{}]interface{}{
+		SymbolIterator: func(ctx *Context) Value {
+			v, _ := ctx.EvalGlobal(`[1, 2, 3][Symbol.iterator]()`)
+			return v
+		},
+		SymbolToStringTag: "Range",
+	})
+	v, err = ctx.EvalGlobal(`[[...range].join(), Object.prototype.toString.call(range)]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"1,2,3", "[object Range]"}, v.InterfaceAndFree())
+
+	// the builtin functions are used regardless of the globals overwritten by script
+	v, err = ctx.EvalGlobal(`
+const original = Symbol;
+globalThis.Symbol = () => "hijacked";
+original.for = original.keyFor = () => undefined;
+Object.defineProperty(original.prototype, "description", { get() { return "hijacked" } });
+[original.iterator, registered]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{SymbolIterator, Symbol{Description: "app", Registered: true}}, v.InterfaceAndFree())
+	unique := ctx.Symbol("unique")
+	assert.True(unique.IsSymbol())
+	unique.Free()
+	registered := ctx.ToJSValue(Symbol{Description: "app", Registered: true})
+	ctx.Globals().Set("registeredAgain", registered)
+	v, err = ctx.EvalGlobal(`registeredAgain === registered`)
+	require.NoError(t, err)
+	assert.Equal(true, v.InterfaceAndFree())
+}
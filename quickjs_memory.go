@@ -0,0 +1,173 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+#include "version.h"
+*/
+import "C"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	stdruntime "runtime"
+	"unsafe"
+)
+
+// UnlimitedMemory is the MallocLimit of the Runtime without memory limit
+const UnlimitedMemory int64 = -1
+
+// MemoryUsage of Runtime, mirror of the JSMemoryUsage in quickjs
+type MemoryUsage struct {
+	MallocSize int64
+	// MallocLimit is UnlimitedMemory unless Runtime.SetMemoryLimit
+	MallocLimit            int64
+	MemoryUsedSize         int64
+	MallocCount            int64
+	MemoryUsedCount        int64
+	AtomCount              int64
+	AtomSize               int64
+	StringCount            int64
+	StringSize             int64
+	ObjectCount            int64
+	ObjectSize             int64
+	PropertyCount          int64
+	PropertySize           int64
+	ShapeCount             int64
+	ShapeSize              int64
+	JSFunctionCount        int64
+	JSFunctionSize         int64
+	JSFunctionCodeSize     int64
+	JSFunctionPC2LineCount int64
+	JSFunctionPC2LineSize  int64
+	CFunctionCount         int64
+	ArrayCount             int64
+	FastArrayCount         int64
+	FastArrayElements      int64
+	BinaryObjectCount      int64
+	BinaryObjectSize       int64
+}
+
+// MemoryUsage compute the memory usage of Runtime
+func (r Runtime) MemoryUsage() MemoryUsage {
+	var s C.JSMemoryUsage
+	C.JS_ComputeMemoryUsage(r.ref, &s)
+	return MemoryUsage{
+		MallocSize:             int64(s.malloc_size),
+		MallocLimit:            int64(s.malloc_limit),
+		MemoryUsedSize:         int64(s.memory_used_size),
+		MallocCount:            int64(s.malloc_count),
+		MemoryUsedCount:        int64(s.memory_used_count),
+		AtomCount:              int64(s.atom_count),
+		AtomSize:               int64(s.atom_size),
+		StringCount:            int64(s.str_count),
+		StringSize:             int64(s.str_size),
+		ObjectCount:            int64(s.obj_count),
+		ObjectSize:             int64(s.obj_size),
+		PropertyCount:          int64(s.prop_count),
+		PropertySize:           int64(s.prop_size),
+		ShapeCount:             int64(s.shape_count),
+		ShapeSize:              int64(s.shape_size),
+		JSFunctionCount:        int64(s.js_func_count),
+		JSFunctionSize:         int64(s.js_func_size),
+		JSFunctionCodeSize:     int64(s.js_func_code_size),
+		JSFunctionPC2LineCount: int64(s.js_func_pc2line_count),
+		JSFunctionPC2LineSize:  int64(s.js_func_pc2line_size),
+		CFunctionCount:         int64(s.c_func_count),
+		ArrayCount:             int64(s.array_count),
+		FastArrayCount:         int64(s.fast_array_count),
+		FastArrayElements:      int64(s.fast_array_elements),
+		BinaryObjectCount:      int64(s.binary_object_count),
+		BinaryObjectSize:       int64(s.binary_object_size),
+	}
+}
+
+// DumpMemoryUsage write the memory usage of Runtime to w, the format is same as the JS_DumpMemoryUsage,
+// except the details of the runtime (e.g. the object classes) are omitted
+func (r Runtime) DumpMemoryUsage(w io.Writer) error {
+	return r.MemoryUsage().Dump(w)
+}
+
+// Dump write the memory usage table to w, the format is same as the JS_DumpMemoryUsage
+func (s MemoryUsage) Dump(w io.Writer) error {
+	// MALLOC_OVERHEAD of quickjs
+	mallocOverhead := 8
+	if stdruntime.GOOS == "darwin" {
+		mallocOverhead = 0
+	}
+
+	b := bufio.NewWriter(w)
+
+	mallocLimit := "unlimited"
+	if s.MallocLimit != UnlimitedMemory {
+		mallocLimit = fmt.Sprint(s.MallocLimit)
+	}
+	fmt.Fprintf(b, "QuickJS memory usage -- BigNum %s version, %d-bit, malloc limit: %s\n\n",
+		C.CONFIG_VERSION, unsafe.Sizeof(uintptr(0))*8, mallocLimit)
+	fmt.Fprintf(b, "%-20s %8s %8s\n", "NAME", "COUNT", "SIZE")
+
+	if s.MallocCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per block)\n",
+			"memory allocated", s.MallocCount, s.MallocSize,
+			float64(s.MallocSize)/float64(s.MallocCount))
+		fmt.Fprintf(b, "%-20s %8d %8d  (%d overhead, %0.1f average slack)\n",
+			"memory used", s.MemoryUsedCount, s.MemoryUsedSize,
+			mallocOverhead, float64(s.MallocSize-s.MemoryUsedSize)/float64(s.MemoryUsedCount))
+	}
+	if s.AtomCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per atom)\n",
+			"atoms", s.AtomCount, s.AtomSize,
+			float64(s.AtomSize)/float64(s.AtomCount))
+	}
+	if s.StringCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per string)\n",
+			"strings", s.StringCount, s.StringSize,
+			float64(s.StringSize)/float64(s.StringCount))
+	}
+	if s.ObjectCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per object)\n",
+			"objects", s.ObjectCount, s.ObjectSize,
+			float64(s.ObjectSize)/float64(s.ObjectCount))
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per object)\n",
+			"  properties", s.PropertyCount, s.PropertySize,
+			float64(s.PropertyCount)/float64(s.ObjectCount))
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per shape)\n",
+			"  shapes", s.ShapeCount, s.ShapeSize,
+			float64(s.ShapeSize)/float64(s.ShapeCount))
+	}
+	if s.JSFunctionCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d %8d\n",
+			"bytecode functions", s.JSFunctionCount, s.JSFunctionSize)
+		fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per function)\n",
+			"  bytecode", s.JSFunctionCount, s.JSFunctionCodeSize,
+			float64(s.JSFunctionCodeSize)/float64(s.JSFunctionCount))
+		if s.JSFunctionPC2LineCount != 0 {
+			fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per function)\n",
+				"  pc2line", s.JSFunctionPC2LineCount, s.JSFunctionPC2LineSize,
+				float64(s.JSFunctionPC2LineSize)/float64(s.JSFunctionPC2LineCount))
+		}
+	}
+	if s.CFunctionCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d\n", "C functions", s.CFunctionCount)
+	}
+	if s.ArrayCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d\n", "arrays", s.ArrayCount)
+		if s.FastArrayCount != 0 {
+			fmt.Fprintf(b, "%-20s %8d\n", "  fast arrays", s.FastArrayCount)
+			fmt.Fprintf(b, "%-20s %8d %8d  (%0.1f per fast array)\n",
+				"  elements", s.FastArrayElements,
+				s.FastArrayElements*int64(unsafe.Sizeof(C.JSValue{})),
+				float64(s.FastArrayElements)/float64(s.FastArrayCount))
+		}
+	}
+	if s.BinaryObjectCount != 0 {
+		fmt.Fprintf(b, "%-20s %8d %8d\n",
+			"binary objects", s.BinaryObjectCount, s.BinaryObjectSize)
+	}
+
+	return b.Flush()
+}
@@ -0,0 +1,49 @@
+package quickjs
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	stdruntime "runtime"
+	"testing"
+)
+
+func TestRuntime_MemoryUsage(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	before := r.MemoryUsage()
+	assert.True(before.MallocSize > 0)
+	assert.True(before.ObjectCount > 0)
+	assert.Equal(UnlimitedMemory, before.MallocLimit)
+
+	v, err := ctx.EvalGlobal(`var items = []; for (let i = 0; i < 1000; i++) { items.push({ i }) }; items.length`)
+	assert.Nil(err)
+	defer v.Free()
+
+	after := r.MemoryUsage()
+	assert.True(after.ObjectCount >= before.ObjectCount+1000)
+	assert.True(after.MallocSize > before.MallocSize)
+	assert.True(after.ArrayCount > 0)
+
+	r.SetMemoryLimit(64 * 1024 * 1024)
+	assert.Equal(int64(64*1024*1024), r.MemoryUsage().MallocLimit)
+
+	buf := &bytes.Buffer{}
+	assert.Nil(r.DumpMemoryUsage(buf))
+	assert.Contains(buf.String(), "QuickJS memory usage")
+	assert.Contains(buf.String(), "malloc limit: 67108864")
+	assert.Contains(buf.String(), "memory allocated")
+	assert.Contains(buf.String(), "objects")
+
+	buf.Reset()
+	assert.Nil(before.Dump(buf))
+	assert.Contains(buf.String(), "malloc limit: unlimited")
+	assert.Contains(buf.String(), "objects")
+	assert.NotContains(buf.String(), "JSObject")
+}
@@ -0,0 +1,277 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"runtime/debug"
+	"strings"
+	"unsafe"
+)
+
+// ErrModuleNotFound is returned by the ModuleLoader which does not provide the module
+var ErrModuleNotFound = errors.New("module not found")
+
+// ModuleLoader resolve and load the ES modules imported by javascript
+type ModuleLoader interface {
+	// Normalize the name imported by the module named base, e.g. resolve the relative path,
+	// the normalized name identifies the module, it is loaded once per context
+	Normalize(base, name string) (string, error)
+	// Load the source of the module by the normalized name
+	Load(name string) (source string, err error)
+}
+
+// SetModuleLoader set the loader of the modules imported by javascript, nil to remove it,
+// the native modules registered are resolved before the loader
+func (r Runtime) SetModuleLoader(loader ModuleLoader) {
+	r.state.moduleLoader = loader
+	r.updateModuleLoader()
+}
+
+// updateModuleLoader install the loader functions of quickjs if there is any module to load
+func (r Runtime) updateModuleLoader() {
+	if r.state.moduleLoader == nil && len(r.state.nativeModules) == 0 {
+		C.ClearModuleLoader(r.ref)
+	} else {
+		C.SetModuleLoader(r.ref)
+	}
+}
+
+// ResolveModuleName resolve the relative name (starts with './' or '../') against the module named base,
+// the other names are cleaned only, e.g. `ResolveModuleName("lib/a.js", "./b.js")` is "lib/b.js"
+func ResolveModuleName(base, name string) string {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		return path.Join(path.Dir(base), name)
+	}
+	return path.Clean(name)
+}
+
+// throwRecovered throw the panic recovered in the callbacks from quickjs as InternalError,
+// the panic must not unwind through the frames of quickjs, unless Runtime.SetRepanic for debugging
+func (ctx *Context) throwRecovered(r interface{}) {
+	if ctx.runtime.state.repanic {
+		panic(r)
+	}
+	ctx.throwPanic(r, debug.Stack())
+}
+
+//export moduleNormalize
+func moduleNormalize(ref *C.JSContext, base *C.char, name *C.char) (result *C.char) {
+	ctx := restoreContext(ref)
+	if ctx == nil || ctx.runtime.state.moduleLoader == nil || ctx.runtime.state.nativeModules[C.GoString(name)] != nil {
+		return C.js_strdup(ref, name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ctx.throwRecovered(r)
+			result = nil
+		}
+	}()
+	normalized, err := ctx.runtime.state.moduleLoader.Normalize(C.GoString(base), C.GoString(name))
+	if err != nil {
+		ctx.ThrowError(fmt.Errorf("could not resolve module '%s': %w", C.GoString(name), err))
+		return nil
+	}
+	ptr := C.CString(normalized)
+	defer C.free(unsafe.Pointer(ptr))
+	return C.js_strdup(ref, ptr)
+}
+
+//export moduleLoader
+func moduleLoader(ref *C.JSContext, name *C.char) (result *C.JSModuleDef) {
+	moduleName := C.GoString(name)
+	ctx := restoreContext(ref)
+	if ctx != nil {
+		if m, ok := ctx.runtime.state.nativeModules[moduleName]; ok {
+			return ctx.newNativeModule(m)
+		}
+	}
+	if ctx == nil || ctx.runtime.state.moduleLoader == nil {
+		cause := C.CString(fmt.Sprintf("could not load module '%s': module loader is not set", moduleName))
+		defer C.free(unsafe.Pointer(cause))
+		C.ThrowReferenceError(ref, cause)
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ctx.throwRecovered(r)
+			result = nil
+		}
+	}()
+	source, err := ctx.runtime.state.moduleLoader.Load(moduleName)
+	if err != nil {
+		ctx.ThrowError(fmt.Errorf("could not load module '%s': %w", moduleName, err))
+		return nil
+	}
+	return ctx.compileModule(source, moduleName)
+}
+
+// LoadModule compile and evaluate the code as module named name, and return the namespace object of the module,
+// the exports are the properties of it, e.g. `default`, the namespace must be freed by the caller
+func (ctx *Context) LoadModule(code, name string) (Value, error) {
+	m, result := ctx.evalModule(code, name, C.JS_EVAL_TYPE_MODULE)
+	if result.IsException() {
+		return result, ctx.Exception()
+	}
+	result.Free()
+	return ctx.moduleNamespace(m)
+}
+
+// Module return the namespace object of the module evaluated by the context, name is the normalized name,
+// e.g. the name imported from the module loader, ErrModuleNotFound is returned if it is not evaluated yet,
+// the namespace must be freed by the caller
+func (ctx *Context) Module(name string) (Value, error) {
+	nameAtom := ctx.Atom(name)
+	defer nameAtom.Free()
+	m := C.JS_FindEvaluatedModule(ctx.ref, nameAtom.ref)
+	if m == nil {
+		return ctx.Undefined(), fmt.Errorf("%w: %s", ErrModuleNotFound, name)
+	}
+	return ctx.moduleNamespace(m)
+}
+
+func (ctx *Context) moduleNamespace(m *C.JSModuleDef) (Value, error) {
+	ns := ctx.newValue(C.JS_GetModuleNamespace(ctx.ref, m))
+	if ns.IsException() {
+		return ns, ctx.Exception()
+	}
+	return ns, nil
+}
+
+// evalModule compile the code as the main module named name and evaluate it
+func (ctx *Context) evalModule(code, name string, flags int) (*C.JSModuleDef, Value) {
+	compiled := ctx.evalFile(code, name, flags|C.JS_EVAL_TYPE_MODULE|C.JS_EVAL_FLAG_COMPILE_ONLY)
+	if compiled.IsException() {
+		return nil, compiled
+	}
+	m := (*C.JSModuleDef)(C.GetValuePtr(compiled.ref))
+	ctx.setImportMeta(m, name, true)
+	return m, ctx.newValue(C.JS_EvalFunction(ctx.ref, compiled.ref))
+}
+
+// compileModule compile the source as module without evaluation, the exception is thrown if failed
+func (ctx *Context) compileModule(source, name string) *C.JSModuleDef {
+	compiled := ctx.evalFile(source, name, C.JS_EVAL_TYPE_MODULE|C.JS_EVAL_FLAG_COMPILE_ONLY)
+	if compiled.IsException() {
+		return nil
+	}
+	// the module is referenced by the context, so it should be freed here
+	m := (*C.JSModuleDef)(C.GetValuePtr(compiled.ref))
+	C.JS_FreeValue(ctx.ref, compiled.ref)
+	ctx.setImportMeta(m, name, false)
+	return m
+}
+
+// ImportMetaFunc return the extra fields of `import.meta` for the module by the normalized name
+type ImportMetaFunc func(name string) map[string]interface{}
+
+// SetImportMeta set the function which returns the extra fields of `import.meta`,
+// `url` is the URL of the module name (see ModuleURL) and `main` is true for the module evaluated by Context directly,
+// they could be overwritten by the fields, the fields are converted by Context.ToJSValue
+func (r Runtime) SetImportMeta(fields ImportMetaFunc) { r.state.importMeta = fields }
+
+// setImportMeta populate the `import.meta` of the module before it is evaluated
+func (ctx *Context) setImportMeta(m *C.JSModuleDef, name string, main bool) {
+	meta := ctx.newValue(C.JS_GetImportMeta(ctx.ref, m))
+	defer meta.Free()
+	if meta.IsException() {
+		return
+	}
+	meta.Set("url", ctx.String(ModuleURL(name)))
+	meta.Set("main", ctx.Bool(main))
+	if ctx.runtime.state.importMeta == nil {
+		return
+	}
+	for field, value := range ctx.runtime.state.importMeta(name) {
+		meta.Set(field, ctx.ToJSValue(value))
+	}
+}
+
+// ModuleURL return the `import.meta.url` of the module by the normalized name, like quickjs-libc does,
+// the names are resolved against the root of the loader, e.g. `ModuleURL("lib/a.js")` is "file:///lib/a.js",
+// the names with scheme like "https://host/a.js" are kept
+func ModuleURL(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return "file://" + path.Join("/", name)
+}
+
+// MapModuleLoader load the modules from the sources by the normalized name
+type MapModuleLoader map[string]string
+
+func (l MapModuleLoader) Normalize(base, name string) (string, error) {
+	normalized := ResolveModuleName(base, name)
+	if _, ok := l[normalized]; !ok {
+		return "", ErrModuleNotFound
+	}
+	return normalized, nil
+}
+
+func (l MapModuleLoader) Load(name string) (string, error) {
+	source, ok := l[name]
+	if !ok {
+		return "", ErrModuleNotFound
+	}
+	return source, nil
+}
+
+// FSModuleLoader load the modules from the file system, the '.js' extension could be omitted
+type FSModuleLoader struct {
+	FS fs.FS
+}
+
+// NewFSModuleLoader create a loader of the modules in fsys
+func NewFSModuleLoader(fsys fs.FS) *FSModuleLoader { return &FSModuleLoader{FS: fsys} }
+
+func (l *FSModuleLoader) Normalize(base, name string) (string, error) {
+	normalized := strings.TrimPrefix(ResolveModuleName(base, name), "/")
+	for _, candidate := range []string{normalized, normalized + ".js"} {
+		if info, err := fs.Stat(l.FS, candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", ErrModuleNotFound
+}
+
+func (l *FSModuleLoader) Load(name string) (string, error) {
+	source, err := fs.ReadFile(l.FS, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrModuleNotFound
+	}
+	return string(source), err
+}
+
+// ChainModuleLoader try the loaders in order, the module is provided by the first loader which
+// does not return ErrModuleNotFound
+type ChainModuleLoader []ModuleLoader
+
+func (l ChainModuleLoader) Normalize(base, name string) (string, error) {
+	for _, loader := range l {
+		normalized, err := loader.Normalize(base, name)
+		if !errors.Is(err, ErrModuleNotFound) {
+			return normalized, err
+		}
+	}
+	return "", ErrModuleNotFound
+}
+
+func (l ChainModuleLoader) Load(name string) (string, error) {
+	for _, loader := range l {
+		source, err := loader.Load(name)
+		if !errors.Is(err, ErrModuleNotFound) {
+			return source, err
+		}
+	}
+	return "", ErrModuleNotFound
+}
@@ -0,0 +1,128 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// NativeModule is an ES module implemented by golang, e.g. `import { sign } from "go:crypto"`,
+// the exports are created when the module is imported by a context the first time
+type NativeModule struct {
+	name    string
+	exports []nativeExport
+}
+
+type nativeExport struct {
+	name   string
+	create func(ctx *Context) (Value, error)
+}
+
+// NewNativeModule declare a module with the name, it could be imported once registered by Runtime.RegisterModule
+func NewNativeModule(name string) *NativeModule { return &NativeModule{name: name} }
+
+// Name of the module
+func (m *NativeModule) Name() string { return m.name }
+
+// Export the golang value converted by Context.Marshal, the JSFunction is exported as native function
+func (m *NativeModule) Export(name string, value interface{}) *NativeModule {
+	return m.ExportLazy(name, func(ctx *Context) (Value, error) {
+		switch fn := value.(type) {
+		case JSFunction:
+			return ctx.NamedFunction(name, 0, fn), nil
+		case func(ctx *Context, this Value, args []Value) Value:
+			return ctx.NamedFunction(name, 0, fn), nil
+		}
+		return ctx.Marshal(value)
+	})
+}
+
+// ExportClass export the golang type as class, see Context.RegisterClass for the constructor
+func (m *NativeModule) ExportClass(name string, constructor interface{}) *NativeModule {
+	return m.ExportLazy(name, func(ctx *Context) (Value, error) { return ctx.RegisterClass(name, constructor) })
+}
+
+// ExportLazy export the value created by fn for each context which imports the module,
+// the ownership of the value is taken by the module, the error is thrown by the import
+func (m *NativeModule) ExportLazy(name string, create func(ctx *Context) (Value, error)) *NativeModule {
+	m.exports = append(m.exports, nativeExport{name: name, create: create})
+	return m
+}
+
+// RegisterModule register the native module, so that it could be imported by javascript of all contexts,
+// it takes precedence over the ModuleLoader for the same name
+func (r Runtime) RegisterModule(m *NativeModule) {
+	if r.state.nativeModules == nil {
+		r.state.nativeModules = make(map[string]*NativeModule)
+	}
+	r.state.nativeModules[m.name] = m
+	r.updateModuleLoader()
+}
+
+// newNativeModule declare the module with the exports in quickjs, the exports are set by nativeModuleInit
+func (ctx *Context) newNativeModule(m *NativeModule) *C.JSModuleDef {
+	namePtr := C.CString(m.name)
+	defer C.free(unsafe.Pointer(namePtr))
+	def := C.NewNativeModule(ctx.ref, namePtr)
+	if def == nil {
+		return nil
+	}
+	for _, export := range m.exports {
+		exportPtr := C.CString(export.name)
+		ret := C.JS_AddModuleExport(ctx.ref, def, exportPtr)
+		C.free(unsafe.Pointer(exportPtr))
+		if ret < 0 {
+			return nil
+		}
+	}
+	return def
+}
+
+//export nativeModuleInit
+func nativeModuleInit(ref *C.JSContext, def *C.JSModuleDef) (result C.int) {
+	ctx := restoreContext(ref)
+	if ctx == nil {
+		return -1
+	}
+	// the factories of ExportLazy are called here, their panics must not unwind through quickjs
+	defer func() {
+		if r := recover(); r != nil {
+			ctx.throwRecovered(r)
+			result = -1
+		}
+	}()
+	nameAtom := Atom{ctx: ctx, ref: C.JS_GetModuleName(ref, def)}
+	name := nameAtom.String()
+	nameAtom.Free()
+	m, ok := ctx.runtime.state.nativeModules[name]
+	if !ok {
+		ctx.ThrowReferenceError("native module '%s' is not registered", name)
+		return -1
+	}
+	for _, export := range m.exports {
+		val, err := export.create(ctx)
+		if err == nil && val.IsException() {
+			err = ctx.Exception()
+		}
+		if err != nil {
+			val.Free()
+			ctx.ThrowError(fmt.Errorf("could not export '%s' of module '%s': %w", export.name, name, err))
+			return -1
+		}
+		exportPtr := C.CString(export.name)
+		ret := C.JS_SetModuleExport(ref, def, exportPtr, val.transfer())
+		C.free(unsafe.Pointer(exportPtr))
+		if ret < 0 {
+			return -1
+		}
+	}
+	return 0
+}
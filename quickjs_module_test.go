@@ -0,0 +1,301 @@
+package quickjs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"path"
+	stdruntime "runtime"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolveModuleName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("lib/b.js", ResolveModuleName("lib/a.js", "./b.js"))
+	assert.Equal("b.js", ResolveModuleName("lib/a.js", "../b.js"))
+	assert.Equal("b.js", ResolveModuleName("code", "./b.js"))
+	assert.Equal("lib/b.js", ResolveModuleName("lib/a.js", "lib/./b.js"))
+	assert.Equal("go:crypto", ResolveModuleName("lib/a.js", "go:crypto"))
+}
+
+func TestModuleURL(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("file:///lib/a.js", ModuleURL("lib/a.js"))
+	assert.Equal("file:///src/main.js", ModuleURL("/src/main.js"))
+	assert.Equal("https://example.com/a.js", ModuleURL("https://example.com/a.js"))
+}
+
+func TestRuntime_SetModuleLoader(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	r.SetModuleLoader(ChainModuleLoader{
+		MapModuleLoader{
+			"config.js": `export const name = "map"`,
+		},
+		NewFSModuleLoader(fstest.MapFS{
+			"config.js":      {Data: []byte(`export const name = "fs"`)},
+			"lib/math.js":    {Data: []byte(`import { double } from "./util"; export const quadruple = (n) => double(double(n))`)},
+			"lib/util.js":    {Data: []byte(`import { name } from "../config.js"; export const double = (n) => n * 2; export { name }`)},
+			"lib/broken.js":  {Data: []byte(`export const = 1`)},
+			"lib/missing.js": {Data: []byte(`import "./nothing.js"`)},
+		}),
+	})
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	v, err := ctx.EvalFile(`
+import { quadruple } from "./lib/math.js";
+import { name } from "./lib/util.js";
+globalThis.result = [quadruple(3), name];
+`, "main.js", 1)
+	require.NoError(t, err)
+	v.Free()
+	v, err = ctx.EvalGlobal(`result`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{int64(12), "map"}, v.InterfaceAndFree())
+
+	_, err = ctx.EvalModule(`import "./lib/missing.js"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "could not resolve module './nothing.js': module not found")
+
+	_, err = ctx.EvalModule(`import "./lib/broken.js"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "SyntaxError")
+
+	r.SetModuleLoader(nil)
+	_, err = ctx.EvalModule(`import "./other.js"`)
+	assert.Error(err)
+}
+
+func TestRuntime_RegisterModule(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+
+	created := 0
+	r.RegisterModule(NewNativeModule("go:crypto").
+		Export("sha256", func(data string) string {
+			sum := sha256.Sum256([]byte(data))
+			return hex.EncodeToString(sum[:])
+		}).
+		Export("version", "1.0").
+		Export("echo", JSFunction(func(ctx *Context, this Value, args []Value) Value { return args[0].Dup() })).
+		ExportClass("Counter", NewClassCounter).
+		ExportLazy("created", func(ctx *Context) (Value, error) {
+			created++
+			return ctx.Int64(int64(created)), nil
+		}))
+	r.RegisterModule(NewNativeModule("go:broken").ExportLazy("value", func(ctx *Context) (Value, error) {
+		return ctx.Undefined(), errors.New("unavailable")
+	}))
+	r.RegisterModule(NewNativeModule("go:panic").ExportLazy("value", func(ctx *Context) (Value, error) {
+		panic("not ready")
+	}))
+
+	ctx := r.NewContext()
+	defer ctx.Free()
+	assert.Equal(0, created)
+
+	_, err := ctx.EvalModule(`
+import { sha256, version, echo, Counter, created } from "go:crypto";
+import * as crypto from "go:crypto";
+const counter = new Counter("c", 1);
+globalThis.result = [sha256("abc").slice(0, 8), version, echo(7), counter.Add(2), created, crypto.created];
+`)
+	require.NoError(t, err)
+	v, err := ctx.EvalGlobal(`result`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"ba7816bf", "1.0", int64(7), int64(3), int64(1), int64(1)}, v.InterfaceAndFree())
+
+	_, err = ctx.EvalModule(`import { created } from "go:crypto"; globalThis.created = created`)
+	require.NoError(t, err)
+	v, err = ctx.EvalGlobal(`created`)
+	require.NoError(t, err)
+	assert.Equal(int64(1), v.InterfaceAndFree())
+
+	_, err = ctx.EvalModule(`import { value } from "go:broken"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "could not export 'value' of module 'go:broken': unavailable")
+
+	// the panic of the factory is thrown as InternalError
+	_, err = ctx.EvalModule(`import { value } from "go:panic"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "InternalError: panic: not ready")
+
+	_, err = ctx.EvalModule(`import { missing } from "go:crypto"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "SyntaxError")
+
+	// each context has its own exports
+	other := r.NewContext()
+	defer other.Free()
+	_, err = other.EvalModule(`import { created } from "go:crypto"`)
+	require.NoError(t, err)
+	assert.Equal(2, created)
+}
+
+func TestContext_LoadModule(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	r.SetModuleLoader(MapModuleLoader{
+		"lib/greeting.js": `export const greeting = "hello"; export let count = 0; export function inc() { count++ }`,
+	})
+	r.RegisterModule(NewNativeModule("go:env").Export("name", "test"))
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	ns, err := ctx.LoadModule(`
+import { greeting, inc } from "./greeting.js";
+import { name } from "go:env";
+export default function handler(req) { inc(); return greeting + " " + req.user + " from " + name }
+export const kind = "handler";
+`, "lib/handler.js")
+	require.NoError(t, err)
+	defer ns.Free()
+	assert.Equal("handler", ns.GetString("kind"))
+
+	handler := ns.Get("default")
+	defer handler.Free()
+	for i := 0; i < 2; i++ {
+		result, err := Call[string](handler, map[string]string{"user": "alice"})
+		require.NoError(t, err)
+		assert.Equal("hello alice from test", result)
+	}
+
+	greeting, err := ctx.Module("lib/greeting.js")
+	require.NoError(t, err)
+	assert.Equal(int64(2), greeting.GetInt64("count"))
+	greeting.Free()
+
+	env, err := ctx.Module("go:env")
+	require.NoError(t, err)
+	assert.Equal("test", env.GetString("name"))
+	env.Free()
+
+	handlerNS, err := ctx.Module("lib/handler.js")
+	require.NoError(t, err)
+	assert.Equal("handler", handlerNS.GetString("kind"))
+	handlerNS.Free()
+
+	_, err = ctx.Module("lib/other.js")
+	assert.Equal("module not found: lib/other.js", err.Error())
+
+	_, err = ctx.LoadModule(`throw new Error("failed")`, "failed.js")
+	require.Error(t, err)
+	assert.Contains(err.Error(), "failed")
+	_, err = ctx.Module("failed.js")
+	assert.Error(err)
+}
+
+func TestContext_DynamicImport(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	r.SetModuleLoader(MapModuleLoader{
+		"lib/meta.js":    `export const meta = import.meta; export const loadSibling = () => import("./sibling.js")`,
+		"lib/sibling.js": `export default "sibling"`,
+	})
+	r.SetImportMeta(func(name string) map[string]interface{} {
+		return map[string]interface{}{"env": "test", "dir": path.Dir(name)}
+	})
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	runJobs := func() {
+		for {
+			if err := ctx.ExecutePendingJob(); err != nil {
+				require.Equal(t, io.EOF, err)
+				return
+			}
+		}
+	}
+
+	_, err := ctx.EvalFile(`
+globalThis.results = [import.meta.url, import.meta.main, import.meta.env];
+import("./lib/meta.js")
+	.then(({ meta, loadSibling }) => {
+		results.push(meta.url, meta.main, meta.dir);
+		return loadSibling();
+	})
+	.then((sibling) => results.push(sibling.default));
+`, "main.js", 1)
+	require.NoError(t, err)
+	runJobs()
+	v, err := ctx.EvalGlobal(`results`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"file:///main.js", true, "test", "file:///lib/meta.js", false, "lib", "sibling"}, v.InterfaceAndFree())
+
+	// scripts import the modules relative to the file name
+	v, err = ctx.EvalFile(`
+import("./lib/sibling.js").then((m) => { globalThis.fromScript = m.default });
+import("./lib/missing.js").catch((err) => { globalThis.missing = err.message });
+`, "script.js", 0)
+	require.NoError(t, err)
+	v.Free()
+	runJobs()
+	v, err = ctx.EvalGlobal(`[fromScript, missing]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{"sibling", "could not resolve module './lib/missing.js': module not found"}, v.InterfaceAndFree())
+}
+
+// panicModuleLoader panics when "normalize-panic" is normalized or "load-panic" is loaded
+type panicModuleLoader struct{ MapModuleLoader }
+
+func (l panicModuleLoader) Normalize(base, name string) (string, error) {
+	if name == "normalize-panic" {
+		panic("normalize failed")
+	}
+	return l.MapModuleLoader.Normalize(base, name)
+}
+
+func (l panicModuleLoader) Load(name string) (string, error) {
+	if name == "load-panic" {
+		panic("load failed")
+	}
+	return l.MapModuleLoader.Load(name)
+}
+
+func TestRuntime_ModuleLoaderPanic(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	r.SetModuleLoader(panicModuleLoader{MapModuleLoader{"load-panic": ``, "ok.js": `export default 1`}})
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	// the panics of the loader are thrown as InternalError instead of crashing the process
+	_, err := ctx.EvalModule(`import "normalize-panic"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "InternalError: panic: normalize failed")
+	var jsErr *Error
+	require.True(t, errors.As(err, &jsErr))
+	assert.Equal("normalize failed", jsErr.Panic)
+
+	_, err = ctx.EvalModule(`import "load-panic"`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "InternalError: panic: load failed")
+
+	_, err = ctx.EvalModule(`import "ok.js"`)
+	assert.NoError(err)
+}
@@ -0,0 +1,126 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"unsafe"
+)
+
+// IntegerPolicy decide how the golang integers are converted to javascript
+type IntegerPolicy int
+
+const (
+	// IntegerAuto convert the safe integers (within ±(2^53-1)) to Number, and the others to BigInt
+	IntegerAuto IntegerPolicy = iota
+	// IntegerAsNumber always convert the integers to Number, the unsafe integers lose precision
+	IntegerAsNumber
+	// IntegerAsBigInt convert the 64-bit integers (int64, uint64, and int, uint on 64-bit platforms) to BigInt
+	IntegerAsBigInt
+)
+
+// maxSafeInteger is `Number.MAX_SAFE_INTEGER`
+const maxSafeInteger = 1<<53 - 1
+
+var bigIntType = reflect.TypeOf(big.Int{})
+var bigFloatType = reflect.TypeOf(big.Float{})
+var bigIntPtrType = reflect.PtrTo(bigIntType)
+var bigFloatPtrType = reflect.PtrTo(bigFloatType)
+
+// SetIntegerPolicy set how the golang integers are converted to javascript in Context.ToJSValue, IntegerAuto by default
+func (ctx *Context) SetIntegerPolicy(policy IntegerPolicy) { ctx.integerPolicy = policy }
+
+// BigInt64 create javascript BigInt from int64
+func (ctx *Context) BigInt64(v int64) Value {
+	return ctx.newValue(C.JS_NewBigInt64(ctx.ref, C.int64_t(v)))
+}
+
+// BigInt create javascript BigInt
+func (ctx *Context) BigInt(v *big.Int) Value {
+	if v.IsInt64() {
+		return ctx.BigInt64(v.Int64())
+	}
+	text := C.CString(v.String())
+	defer C.free(unsafe.Pointer(text))
+	return ctx.newValue(C.JS_NewBigIntFromString(ctx.ref, text))
+}
+
+// BigFloat create javascript BigFloat, it is rounded to the precision of javascript BigFloat environment
+func (ctx *Context) BigFloat(v *big.Float) Value {
+	text := v.Text('g', -1)
+	if v.IsInf() {
+		text = "Infinity"
+		if v.Signbit() {
+			text = "-Infinity"
+		}
+	}
+	ptr := C.CString(text)
+	defer C.free(unsafe.Pointer(ptr))
+	return ctx.newValue(C.JS_NewBigFloatFromString(ctx.ref, ptr))
+}
+
+// intToJS convert golang signed integer of bits to javascript by the integer policy
+func (ctx *Context) intToJS(i int64, bits int) Value {
+	switch ctx.integerPolicy {
+	case IntegerAsNumber:
+		return ctx.Int64(i)
+	case IntegerAsBigInt:
+		if bits == 64 {
+			return ctx.BigInt64(i)
+		}
+		return ctx.Int64(i)
+	}
+	if i < -maxSafeInteger || i > maxSafeInteger {
+		return ctx.BigInt64(i)
+	}
+	return ctx.Int64(i)
+}
+
+// uintToJS convert golang unsigned integer of bits to javascript by the integer policy
+func (ctx *Context) uintToJS(u uint64, bits int) Value {
+	switch ctx.integerPolicy {
+	case IntegerAsNumber:
+		return ctx.Float64(float64(u))
+	case IntegerAsBigInt:
+		if bits == 64 {
+			return ctx.BigUint64(u)
+		}
+		return ctx.Int64(int64(u))
+	}
+	if u > maxSafeInteger {
+		return ctx.BigUint64(u)
+	}
+	return ctx.Int64(int64(u))
+}
+
+// exportNumber convert javascript numbers to golang, the integer Numbers within the safe range are int64,
+// the other Numbers are float64, BigInt is *big.Int, BigFloat and BigDecimal are *big.Float
+func (v Value) exportNumber() interface{} {
+	switch {
+	case v.IsBigInt():
+		return v.BigInt()
+	case v.IsBigFloat() || v.IsBigDecimal():
+		return v.BigFloat()
+	case v.IsIntNumber():
+		return v.Int64()
+	}
+	f := v.Float64()
+	if f == math.Trunc(f) && math.Abs(f) <= maxSafeInteger && !(f == 0 && math.Signbit(f)) {
+		return int64(f)
+	}
+	return f
+}
+
+// isNumeric return true for Number, BigInt, BigFloat and BigDecimal
+func (v Value) isNumeric() bool {
+	return v.IsNumber() || v.IsBigInt() || v.IsBigFloat() || v.IsBigDecimal()
+}
@@ -0,0 +1,131 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/big"
+	"reflect"
+	stdruntime "runtime"
+	"testing"
+)
+
+func TestContext_IntegerPolicy(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	typeOf := func(value interface{}) string {
+		ctx.Globals().SetGoValue("value", value)
+		v, err := ctx.EvalGlobal(`typeof value`)
+		require.NoError(t, err)
+		return v.InterfaceAndFree().(string)
+	}
+
+	assert.Equal("number", typeOf(math.MaxInt32+1))
+	assert.Equal("number", typeOf(int64(maxSafeInteger)))
+	assert.Equal("bigint", typeOf(int64(maxSafeInteger+1)))
+	assert.Equal("bigint", typeOf(int64(math.MinInt64)))
+	assert.Equal("number", typeOf(uint64(42)))
+	assert.Equal("bigint", typeOf(uint64(math.MaxUint64)))
+
+	ctx.Globals().SetGoValue("value", math.MaxInt32+1)
+	v, err := ctx.EvalGlobal(`value === 2 ** 31`)
+	require.NoError(t, err)
+	assert.Equal(true, v.InterfaceAndFree())
+
+	ctx.SetIntegerPolicy(IntegerAsNumber)
+	assert.Equal("number", typeOf(int64(math.MaxInt64)))
+	assert.Equal("number", typeOf(uint64(math.MaxUint64)))
+
+	ctx.SetIntegerPolicy(IntegerAsBigInt)
+	assert.Equal("bigint", typeOf(int64(1)))
+	assert.Equal("bigint", typeOf(uint64(1)))
+	assert.Equal("number", typeOf(int32(1)))
+}
+
+func TestContext_BigNumbers(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	ctx.Globals().SetGoValue("huge", huge)
+	ctx.Globals().SetGoValue("small", big.NewInt(-7))
+	ctx.Globals().SetGoValue("half", big.NewFloat(0.5))
+	v, err := ctx.EvalGlobal(`[typeof huge, huge + 1n, typeof small, small, typeof half, half * 2l]`)
+	require.NoError(t, err)
+	values := v.InterfaceAndFree().([]interface{})
+	assert.Equal("bigint", values[0])
+	assert.Equal(new(big.Int).Add(huge, big.NewInt(1)), values[1])
+	assert.Equal("bigint", values[2])
+	assert.Equal(big.NewInt(-7), values[3])
+	assert.Equal("bigfloat", values[4])
+	assert.Equal(0, big.NewFloat(1).Cmp(values[5].(*big.Float)))
+
+	// the numbers are not created by the globals overwritten by script
+	v, err = ctx.EvalGlobal(`globalThis.BigInt = globalThis.BigFloat = () => "hijacked"`)
+	require.NoError(t, err)
+	v.Free()
+	bigInt := ctx.BigInt(huge)
+	assert.True(bigInt.IsBigInt())
+	assert.Equal(huge, bigInt.BigInt())
+	bigInt.Free()
+	bigFloat := ctx.BigFloat(big.NewFloat(0.5))
+	assert.True(bigFloat.IsBigFloat())
+	bigFloat.Free()
+
+	// integer Numbers are int64 regardless of the internal representation
+	v, err = ctx.EvalGlobal(`[1, 2 ** 40, 0.5, 2 ** 60, -0]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{int64(1), int64(1) << 40, 0.5, math.Pow(2, 60), math.Copysign(0, -1)}, v.InterfaceAndFree())
+}
+
+func TestValue_ToReflectValueOverflow(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	v, err := ctx.EvalGlobal(`300`)
+	require.NoError(t, err)
+	_, err = v.ToReflectValue(reflect.TypeOf(int8(0)))
+	assert.EqualError(err, "decode: 300 overflows int8")
+	goValue, err := v.ToReflectValue(reflect.TypeOf(uint16(0)))
+	assert.NoError(err)
+	assert.Equal(uint16(300), goValue.Interface())
+	v.Free()
+
+	v, err = ctx.EvalGlobal(`2n ** 64n - 1n`)
+	require.NoError(t, err)
+	goValue, err = v.ToReflectValue(reflect.TypeOf(uint64(0)))
+	assert.NoError(err)
+	assert.Equal(uint64(math.MaxUint64), goValue.Interface())
+	_, err = v.ToReflectValue(reflect.TypeOf(int64(0)))
+	assert.Error(err)
+	v.Free()
+
+	ctx.Globals().SetGoValue("byte", func(b byte) byte { return b })
+	_, err = ctx.EvalGlobal(`byte(256)`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "TypeError: argument 0: decode: 256 overflows uint8")
+	_, err = ctx.EvalGlobal(`byte(-1)`)
+	assert.Error(err)
+	v, err = ctx.EvalGlobal(`byte("7")`)
+	require.NoError(t, err)
+	assert.Equal(int64(7), v.InterfaceAndFree())
+}
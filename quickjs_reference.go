@@ -0,0 +1,100 @@
+package quickjs
+
+import (
+	"errors"
+	stdruntime "runtime"
+	"sync"
+)
+
+// ErrContextFreed is returned by the golang functions wrapping javascript functions once the Context is freed
+var ErrContextFreed = errors.New("context is freed")
+
+// jsReference keep a javascript value referenced by a golang value, e.g. the javascript function of
+// a golang function converted by Value.Interface, the javascript value is released once the reference
+// is collected by the golang garbage collector, or the Context is freed
+type jsReference struct {
+	ctx *Context
+	id  uint64
+}
+
+// referenceStore of the javascript values referenced by golang, the finalizers run on another goroutine,
+// so the collected references are queued and released on the javascript thread
+type referenceStore struct {
+	lock     sync.Mutex
+	nextID   uint64
+	values   map[uint64]Value
+	released []uint64
+	closed   bool
+}
+
+// reference take the ownership of v until the reference is collected, the references collected before are released
+func (ctx *Context) reference(v Value) *jsReference {
+	ctx.releaseReferences()
+	store := &ctx.references
+	store.lock.Lock()
+	store.nextID++
+	ref := &jsReference{ctx: ctx, id: store.nextID}
+	if store.values == nil {
+		store.values = make(map[uint64]Value)
+	}
+	store.values[ref.id] = v
+	store.lock.Unlock()
+	stdruntime.SetFinalizer(ref, (*jsReference).release)
+	return ref
+}
+
+// releaseReferences free the values of the references collected, it must be invoked on the javascript thread
+func (ctx *Context) releaseReferences() {
+	store := &ctx.references
+	store.lock.Lock()
+	var values []Value
+	for _, id := range store.released {
+		if v, ok := store.values[id]; ok {
+			values = append(values, v)
+			delete(store.values, id)
+		}
+	}
+	store.released = nil
+	store.lock.Unlock()
+	for _, v := range values {
+		v.Free()
+	}
+}
+
+// freeReferences free the values of all references when the context is freed
+func (ctx *Context) freeReferences() {
+	store := &ctx.references
+	store.lock.Lock()
+	values := store.values
+	store.values = nil
+	store.released = nil
+	store.closed = true
+	store.lock.Unlock()
+	for _, v := range values {
+		v.Free()
+	}
+}
+
+// value of the reference, ErrContextFreed is returned once the context is freed,
+// the caller must keep the reference alive (e.g. runtime.KeepAlive) while the value is used
+func (ref *jsReference) value() (Value, error) {
+	store := &ref.ctx.references
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	v, ok := store.values[ref.id]
+	if !ok {
+		return Value{}, ErrContextFreed
+	}
+	return v, nil
+}
+
+// release queue the value to be freed on the javascript thread, it is the finalizer of the reference
+func (ref *jsReference) release() {
+	stdruntime.SetFinalizer(ref, nil)
+	store := &ref.ctx.references
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if !store.closed {
+		store.released = append(store.released, ref.id)
+	}
+}
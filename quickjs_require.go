@@ -0,0 +1,202 @@
+package quickjs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// NodeModuleLoader resolve the modules from the file system like node.js, the relative names are resolved
+// with the '.js' and '.json' extensions or as directories with package.json or index.js, the bare names are
+// looked up in the node_modules directories of the importing module and its ancestors
+type NodeModuleLoader struct {
+	FS fs.FS
+}
+
+// NewNodeModuleLoader create a loader of the node.js style modules in fsys
+func NewNodeModuleLoader(fsys fs.FS) *NodeModuleLoader { return &NodeModuleLoader{FS: fsys} }
+
+func (l *NodeModuleLoader) Normalize(base, name string) (string, error) {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") || strings.HasPrefix(name, "/") {
+		if resolved, ok := l.resolve(strings.TrimPrefix(ResolveModuleName(base, name), "/")); ok {
+			return resolved, nil
+		}
+		return "", ErrModuleNotFound
+	}
+	for dir := path.Dir(base); ; dir = path.Dir(dir) {
+		if path.Base(dir) != "node_modules" {
+			if resolved, ok := l.resolve(path.Join(dir, "node_modules", name)); ok {
+				return resolved, nil
+			}
+		}
+		if dir == "." || dir == "/" {
+			return "", ErrModuleNotFound
+		}
+	}
+}
+
+// resolve the name as file or directory
+func (l *NodeModuleLoader) resolve(name string) (string, bool) {
+	if resolved, ok := l.resolveFile(name); ok {
+		return resolved, true
+	}
+	if data, err := fs.ReadFile(l.FS, path.Join(name, "package.json")); err == nil {
+		pkg := struct {
+			Main string `json:"main"`
+		}{}
+		if err := json.Unmarshal(data, &pkg); err == nil && len(pkg.Main) > 0 {
+			main := path.Join(name, pkg.Main)
+			if resolved, ok := l.resolveFile(main); ok {
+				return resolved, true
+			}
+			if resolved, ok := l.resolveFile(path.Join(main, "index")); ok {
+				return resolved, true
+			}
+		}
+	}
+	return l.resolveFile(path.Join(name, "index"))
+}
+
+func (l *NodeModuleLoader) resolveFile(name string) (string, bool) {
+	for _, candidate := range []string{name, name + ".js", name + ".json"} {
+		if info, err := fs.Stat(l.FS, candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func (l *NodeModuleLoader) Load(name string) (string, error) {
+	source, err := fs.ReadFile(l.FS, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrModuleNotFound
+	}
+	return string(source), err
+}
+
+// commonJS is the CommonJS modules of a context, the modules are cached by the normalized names,
+// the modules are nil once it is freed, then the `require` functions created by it throw TypeError
+type commonJS struct {
+	ctx     *Context
+	loader  ModuleLoader
+	modules map[string]Value
+}
+
+// EnableRequire define the CommonJS `require` function in the global scope, the modules are resolved and loaded
+// by the loader, e.g. NodeModuleLoader, they are evaluated once and cached by the context,
+// the '.json' modules are parsed as JSON
+func (ctx *Context) EnableRequire(loader ModuleLoader) {
+	if ctx.commonJS != nil {
+		ctx.commonJS.free()
+	}
+	ctx.commonJS = &commonJS{ctx: ctx, loader: loader, modules: make(map[string]Value)}
+	ctx.Globals().Set("require", ctx.commonJS.requireFunction(""))
+}
+
+// Require the CommonJS module from golang, the name is resolved as required by the module in the root directory,
+// the exports must be freed by the caller
+func (ctx *Context) Require(name string) (Value, error) {
+	if ctx.commonJS == nil {
+		return ctx.Undefined(), errors.New("require is not enabled, please invoke quickjs.Context.EnableRequire firstly")
+	}
+	exports := ctx.commonJS.require("", name)
+	if exports.IsException() {
+		return exports, ctx.Exception()
+	}
+	return exports, nil
+}
+
+func (c *commonJS) free() {
+	for _, module := range c.modules {
+		module.Free()
+	}
+	c.modules = nil
+}
+
+// requireFunction create the `require` function of the module named base
+func (c *commonJS) requireFunction(base string) Value {
+	return c.ctx.NamedFunction("require", 1, func(ctx *Context, this Value, args []Value) Value {
+		if len(args) == 0 || !args[0].IsString() {
+			return ctx.ThrowTypeError("the module name must be a string")
+		}
+		return c.require(base, args[0].String())
+	})
+}
+
+// require return the exports of the module required by the module named base, the exception is thrown if failed,
+// the module is cached before it is evaluated, so that the cyclic requires get the partial exports
+func (c *commonJS) require(base, name string) Value {
+	ctx := c.ctx
+	if c.modules == nil {
+		return ctx.ThrowTypeError("require is disabled, the modules of it have been released")
+	}
+	filename, err := c.loader.Normalize(base, name)
+	if err != nil {
+		return ctx.ThrowError(fmt.Errorf("cannot find module '%s': %w", name, err))
+	}
+	if module, ok := c.modules[filename]; ok {
+		return module.Get("exports")
+	}
+	source, err := c.loader.Load(filename)
+	if err != nil {
+		return ctx.ThrowError(fmt.Errorf("could not load module '%s': %w", filename, err))
+	}
+
+	module := ctx.Object()
+	module.Set("id", ctx.String(filename))
+	module.Set("filename", ctx.String(filename))
+	module.Set("loaded", ctx.Bool(false))
+	module.Set("exports", ctx.Object())
+	// the module is kept by the cache and here, the cache could be released by the module, e.g. EnableRequire
+	c.modules[filename] = module.Dup()
+	defer module.Free()
+
+	if result := c.evaluate(module, filename, source); result.IsException() {
+		if cached, ok := c.modules[filename]; ok {
+			delete(c.modules, filename)
+			cached.Free()
+		}
+		return result
+	}
+	module.Set("loaded", ctx.Bool(true))
+	return module.Get("exports")
+}
+
+// evaluate the source of the module, the exports are set to the module
+func (c *commonJS) evaluate(module Value, filename, source string) Value {
+	ctx := c.ctx
+	if path.Ext(filename) == ".json" {
+		exports := ctx.ParseJson(source)
+		if exports.IsException() {
+			return exports
+		}
+		module.Set("exports", exports)
+		return ctx.Undefined()
+	}
+
+	// the wrapper starts at the first line, so that the line numbers of the stack are not changed
+	wrapper := ctx.evalFile("(function (exports, require, module, __filename, __dirname) {"+source+"\n})", filename, 0)
+	if wrapper.IsException() {
+		return wrapper
+	}
+	defer wrapper.Free()
+
+	exports := module.Get("exports")
+	defer exports.Free()
+	require := c.requireFunction(filename)
+	defer require.Free()
+	filenameValue := ctx.String(filename)
+	defer filenameValue.Free()
+	dirnameValue := ctx.String(path.Dir(filename))
+	defer dirnameValue.Free()
+
+	result := wrapper.CallWithContext(exports, exports, require, module, filenameValue, dirnameValue)
+	if result.IsException() {
+		return result
+	}
+	result.Free()
+	return ctx.Undefined()
+}
@@ -0,0 +1,96 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+	"testing/fstest"
+)
+
+var requireFS = fstest.MapFS{
+	"app/main.js": {Data: []byte(`
+const { pad } = require("left-pad");
+const config = require("./config");
+const a = require("./cycle/a");
+exports.result = [pad("7", 3), config.name, a.done, a.fromB, require("./cycle/b").done, __filename, __dirname];
+`)},
+	"app/config.json":                         {Data: []byte(`{ "name": "demo" }`)},
+	"app/cycle/a.js":                          {Data: []byte(`exports.done = false; const b = require("./b"); exports.fromB = b.sawA; exports.done = true`)},
+	"app/cycle/b.js":                          {Data: []byte(`const a = require("./a"); exports.sawA = a.done; exports.done = true`)},
+	"app/broken.js":                           {Data: []byte(`require("./config"); throw new Error("broken")`)},
+	"node_modules/left-pad/package.json":      {Data: []byte(`{ "main": "lib/pad" }`)},
+	"node_modules/left-pad/lib/pad.js":        {Data: []byte(`const repeat = require("repeat"); module.exports = { pad: (s, n) => repeat("0", n - s.length) + s }`)},
+	"node_modules/left-pad/node_modules/x.js": {Data: []byte(``)},
+	"node_modules/repeat/index.js":            {Data: []byte(`module.exports = (s, n) => s.repeat(n)`)},
+}
+
+func TestContext_Require(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	_, err := ctx.Require("./app/main")
+	assert.Error(err)
+
+	ctx.EnableRequire(NewNodeModuleLoader(requireFS))
+	exports, err := ctx.Require("./app/main")
+	require.NoError(t, err)
+	result := exports.Get("result")
+	assert.Equal([]interface{}{"007", "demo", true, false, true, "app/main.js", "app"}, result.InterfaceAndFree())
+	exports.Free()
+
+	// the modules are cached by the context
+	v, err := ctx.EvalGlobal(`require("./app/main") === require("./app/main.js") && require("repeat") === require("./node_modules/repeat")`)
+	require.NoError(t, err)
+	assert.Equal(true, v.InterfaceAndFree())
+
+	_, err = ctx.EvalGlobal(`require("./app/broken")`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "Error: broken")
+	_, err = ctx.EvalGlobal(`require("./app/broken")`)
+	assert.Error(err)
+
+	_, err = ctx.EvalGlobal(`require("missing")`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "cannot find module 'missing': module not found")
+	_, err = ctx.EvalGlobal(`require("x")`)
+	assert.Error(err)
+
+	// the require function of the previous loader is disabled
+	v, err = ctx.EvalGlobal(`var previous = require; previous`)
+	require.NoError(t, err)
+	v.Free()
+	ctx.EnableRequire(NewNodeModuleLoader(requireFS))
+	_, err = ctx.EvalGlobal(`previous("repeat")`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "TypeError: require is disabled")
+	v, err = ctx.EvalGlobal(`require("repeat")("a", 2)`)
+	require.NoError(t, err)
+	assert.Equal("aa", v.InterfaceAndFree())
+}
+
+func TestNodeModuleLoader_Normalize(t *testing.T) {
+	assert := assert.New(t)
+	loader := NewNodeModuleLoader(requireFS)
+
+	resolved := func(base, name string) string {
+		normalized, err := loader.Normalize(base, name)
+		if err != nil {
+			return err.Error()
+		}
+		return normalized
+	}
+	assert.Equal("app/config.json", resolved("app/main.js", "./config"))
+	assert.Equal("app/cycle/b.js", resolved("app/cycle/a.js", "./b"))
+	assert.Equal("node_modules/left-pad/lib/pad.js", resolved("app/main.js", "left-pad"))
+	assert.Equal("node_modules/repeat/index.js", resolved("node_modules/left-pad/lib/pad.js", "repeat"))
+	assert.Equal("node_modules/left-pad/node_modules/x.js", resolved("node_modules/left-pad/lib/pad.js", "x"))
+	assert.Equal("module not found", resolved("app/main.js", "x"))
+	assert.Equal("app/main.js", resolved("", "/app/main"))
+}
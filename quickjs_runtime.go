@@ -1,6 +1,9 @@
 package quickjs
 
 import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -18,21 +21,92 @@ import "C"
 
 // Runtime for quickjs
 type Runtime struct {
-	ref *C.JSRuntime
+	ref   *C.JSRuntime
+	state *runtimeState
 }
 
+// runtimeState is shared by all copies of a Runtime
+type runtimeState struct {
+	interrupt  *interruptScope
+	repanic    bool
+	converters map[reflect.Type]Converter
+	leaks      *leakTracker
+	// contexts of the runtime, for the callbacks which only know the *C.JSContext
+	contexts      map[*C.JSContext]*Context
+	moduleLoader  ModuleLoader
+	nativeModules map[string]*NativeModule
+	importMeta    ImportMetaFunc
+	// class ids of the golang types registered by Context.RegisterClass
+	classIDs   map[reflect.Type]C.JSClassID
+	classTypes map[C.JSClassID]reflect.Type
+}
+
+var runtimeLock sync.Mutex
+var runtimeStore = make(map[*C.JSRuntime]*runtimeState)
+
 // NewRuntime for javascript
 func NewRuntime() Runtime {
-	rt := Runtime{ref: C.JS_NewRuntime()}
+	rt := Runtime{ref: C.JS_NewRuntime(), state: &runtimeState{contexts: make(map[*C.JSContext]*Context)}}
 	C.JS_SetCanBlock(rt.ref, C.int(1))
+	C.NewFuncPtrClass(rt.ref, funcPtrClassID)
+	C.NewGoValueClass(rt.ref, goValueClassID)
+	runtimeLock.Lock()
+	defer runtimeLock.Unlock()
+	runtimeStore[rt.ref] = rt.state
 	return rt
 }
 
-// RunGC to perform garbage collection for runtime
-func (r Runtime) RunGC() { C.JS_RunGC(r.ref) }
+func restoreRuntimeState(ref *C.JSRuntime) *runtimeState {
+	runtimeLock.Lock()
+	defer runtimeLock.Unlock()
+	return runtimeStore[ref]
+}
+
+func restoreContext(ref *C.JSContext) *Context {
+	state := restoreRuntimeState(C.JS_GetRuntime(ref))
+	if state == nil {
+		return nil
+	}
+	return state.contexts[ref]
+}
+
+// RunGC to perform garbage collection for runtime, the javascript values referenced by the golang values
+// collected by the golang garbage collector (e.g. the exported functions) are released first
+func (r Runtime) RunGC() {
+	for _, ctx := range r.state.contexts {
+		ctx.releaseReferences()
+	}
+	C.JS_RunGC(r.ref)
+}
 
-// Free runtime, it will raise error when assert failed when something is not free
+// Free runtime, it will raise error when assert failed when something is not free,
+// unless Runtime.TrackLeaks, the leaks are reported to the callback and the runtime is not released then
 func (r Runtime) Free() {
+	if leaks := r.state.leaks; leaks != nil {
+		C.JS_FreeRuntimePending(r.ref)
+		if report := r.LeakReport(); report.Leaked() {
+			if leaks.onLeak == nil {
+				panic(fmt.Errorf("quickjs: runtime is not freed, %s", report))
+			}
+			leaks.onLeak(report)
+			return
+		}
+	}
+	r.free()
+}
+
+// ForceFree release the runtime even if some objects are leaked, e.g. after the leaks are reported by
+// Runtime.Free, the leaked objects are abandoned without finalization, so their Values must not be used anymore
+func (r Runtime) ForceFree() {
+	C.JS_FreeRuntimePending(r.ref)
+	C.JS_AbandonGCObjects(r.ref)
+	r.free()
+}
+
+func (r Runtime) free() {
+	runtimeLock.Lock()
+	delete(runtimeStore, r.ref)
+	runtimeLock.Unlock()
 	C.JS_FreeRuntime(r.ref)
 }
 
@@ -46,6 +120,12 @@ func (r Runtime) SetMemoryLimit(limit int64) {
 	C.JS_SetMemoryLimit(r.ref, C.size_t(limit))
 }
 
+// SetRepanic re-panic the panics of golang functions called from javascript instead of
+// converting them to javascript exceptions, the process will crash, so it is only for debugging
+func (r Runtime) SetRepanic(repanic bool) {
+	r.state.repanic = repanic
+}
+
 // NewContext for quickjs
 func (r Runtime) NewContext() *Context {
 	ref := C.JS_NewContext(r.ref)
@@ -56,6 +136,11 @@ func (r Runtime) NewContext() *Context {
 	C.JS_EnableBignumExt(ref, C.int(1))
 
 	ctx := &Context{ref: ref, runtime: &r}
+	r.state.contexts[ref] = ctx
+	if r.state.leaks != nil {
+		r.state.leaks.track(unsafe.Pointer(ref), 1)
+	}
+	ctx.captureIntrinsics()
 
 	return ctx
 }
@@ -76,10 +161,14 @@ var funcPtrClassID C.JSClassID
 func init() { C.JS_NewClassID(&funcPtrClassID) }
 
 func storeFuncPtr(v funcEntry) int64 {
-	id := atomic.AddInt64(&funcPtrLen, 1) - 1
+	id := atomic.AddInt64(&funcPtrLen, 1)
 	funcPtrLock.Lock()
 	defer funcPtrLock.Unlock()
 	funcPtrStore[id] = v
+	if v.ctx.funcPtrs == nil {
+		v.ctx.funcPtrs = make(map[int64]struct{})
+	}
+	v.ctx.funcPtrs[id] = struct{}{}
 	return id
 }
 
@@ -89,31 +178,88 @@ func restoreFuncPtr(ptr int64) funcEntry {
 	return funcPtrStore[ptr]
 }
 
-//func freeFuncPtr(ptr int64) {
-//	funcPtrLock.Lock()
-//	defer funcPtrLock.Unlock()
-//	delete(funcPtrStore, ptr)
-//}
+func freeFuncPtr(ptr int64) {
+	funcPtrLock.Lock()
+	defer funcPtrLock.Unlock()
+	if entry, ok := funcPtrStore[ptr]; ok {
+		delete(entry.ctx.funcPtrs, ptr)
+		delete(funcPtrStore, ptr)
+	}
+}
+
+// freeContextFuncPtrs release all functions registered by the Context
+func freeContextFuncPtrs(ctx *Context) {
+	funcPtrLock.Lock()
+	defer funcPtrLock.Unlock()
+	for ptr := range ctx.funcPtrs {
+		delete(funcPtrStore, ptr)
+	}
+	ctx.funcPtrs = nil
+}
+
+//export funcPtrFinalizer
+func funcPtrFinalizer(rt *C.JSRuntime, val C.JSValue) {
+	freeFuncPtr(int64(C.GetOpaqueID(val, funcPtrClassID)))
+}
+
+const (
+	funcMagicFunction    = 0
+	funcMagicConstructor = 1
+)
 
 //export proxy
-func proxy(ctx *C.JSContext, thisVal C.JSValueConst, argc C.int, argv *C.JSValueConst) C.JSValue {
+func proxy(ctx *C.JSContext, thisVal C.JSValueConst, argc C.int, argv *C.JSValueConst, magic C.int, funcData *C.JSValue) (result C.JSValue) {
 	// The maximum capacity of the following two slices is limited to (2^29)-1 to remain compatible
 	// with 32-bit platforms. The size of a `*C.char` (a pointer) is 4 Byte on a 32-bit system
 	// and (2^29)*4 == math.MaxInt32 + 1. -- See issue golang/go#13656
-	refs := (*[(1 << 29) - 1]C.JSValueConst)(unsafe.Pointer(argv))[:argc:argc]
+	var refs []C.JSValueConst
+	if argc > 0 {
+		refs = (*[(1 << 29) - 1]C.JSValueConst)(unsafe.Pointer(argv))[:argc:argc]
+	}
 
-	id := C.int64_t(0)
-	C.JS_ToInt64(ctx, &id, refs[0])
+	id := C.GetOpaqueID(*funcData, funcPtrClassID)
 
 	entry := restoreFuncPtr(int64(id))
+	if entry.fn == nil {
+		cause := C.CString("function has been released")
+		defer C.free(unsafe.Pointer(cause))
+		return C.ThrowReferenceError(ctx, cause)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if entry.ctx.runtime.state.repanic {
+				panic(r)
+			}
+			result = entry.ctx.throwPanic(r, debug.Stack()).transfer()
+		}
+	}()
 
-	args := make([]Value, len(refs)-1)
+	args := make([]Value, len(refs))
 	for i := 0; i < len(args); i++ {
 		args[i].ctx = entry.ctx
-		args[i].ref = refs[1+i]
+		args[i].ref = refs[i]
 	}
 
-	result := entry.fn(entry.ctx, Value{ctx: entry.ctx, ref: thisVal}, args)
+	this := Value{ctx: entry.ctx, ref: thisVal}
+
+	if magic == funcMagicConstructor {
+		// constructor is invoked with the new.target as this
+		if !this.IsConstructor() {
+			return entry.ctx.ThrowTypeError("constructor requires 'new'").transfer()
+		}
+		proto := this.Get("prototype")
+		defer proto.Free()
+		// the instance is freed even if fn panics
+		instance := entry.ctx.newValue(C.JS_NewObjectProto(ctx, proto.ref))
+		defer instance.Free()
+		constructed := entry.fn(entry.ctx, instance, args)
+		if constructed.IsException() || constructed.getTag() == JsTagOBJECT {
+			return constructed.transfer()
+		}
+		constructed.Free()
+		return instance.Dup().transfer()
+	}
 
-	return result.ref
+	return entry.fn(entry.ctx, this, args).transfer()
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	stdruntime "runtime"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -289,7 +290,212 @@ func TestValue_Decode(t *testing.T) {
 	assert.Nil(err)
 	assert.True(v2.IsObject())
 	structA := &DecodeStructBase{}
-	v2.Decode(structA)
+	assert.NoError(v2.Decode(structA))
 	assert.Equal(1, structA.A.B)
 
 }
+
+func funcPtrStoreLen() int {
+	funcPtrLock.Lock()
+	defer funcPtrLock.Unlock()
+	return len(funcPtrStore)
+}
+
+func TestContext_FunctionReleasedByGC(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	runtime := NewRuntime()
+	defer runtime.Free()
+	context := runtime.NewContext()
+	defer context.Free()
+
+	before := funcPtrStoreLen()
+	for i := 0; i < 100; i++ {
+		f := context.Function(func(ctx *Context, this Value, args []Value) Value {
+			return ctx.Undefined()
+		})
+		f.Free()
+	}
+	runtime.RunGC()
+	assert.Equal(before, funcPtrStoreLen())
+
+	context.Globals().SetFunction("kept", func(ctx *Context, this Value, args []Value) Value {
+		return ctx.Int32(1)
+	})
+	runtime.RunGC()
+	assert.Equal(before+1, funcPtrStoreLen())
+
+	result, err := context.EvalGlobal("kept()")
+	require.NoError(t, err)
+	assert.Equal(int32(1), result.Int32())
+
+	context.Globals().DeleteProperty("kept")
+	runtime.RunGC()
+	assert.Equal(before, funcPtrStoreLen())
+}
+
+func TestContext_FunctionReleasedByContextFree(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	before := funcPtrStoreLen()
+	for i := 0; i < 10; i++ {
+		runtime := NewRuntime()
+		context := runtime.NewContext()
+		context.Globals().SetGoValue("Demo", DemoObject{})
+		context.Globals().SetGoValue("add", func(a, b int) int { return a + b })
+		assert.True(funcPtrStoreLen() > before)
+		context.Free()
+		assert.Equal(before, funcPtrStoreLen())
+		runtime.Free()
+	}
+}
+
+func TestContext_NamedFunction(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	runtime := NewRuntime()
+	defer runtime.Free()
+	context := runtime.NewContext()
+	defer context.Free()
+
+	context.Globals().Set("sum", context.NamedFunction("sum", 2, func(ctx *Context, this Value, args []Value) Value {
+		return ctx.Int64(args[0].Int64() + args[1].Int64())
+	}))
+	context.Globals().SetFunction("fail", func(ctx *Context, this Value, args []Value) Value {
+		return ctx.ThrowError(errors.New("failed"))
+	})
+	context.Globals().SetGoValue("Demo", DemoObject{})
+
+	result, err := context.EvalGlobal(`[sum.name, sum.length, sum(1, 2), fail.name, Demo.Add.name, Demo.Add.length]`)
+	require.NoError(t, err)
+	defer result.Free()
+	assert.Equal([]interface{}{"sum", int64(2), int64(3), "fail", "Add", int64(2)}, result.Interface())
+
+	_, err = context.EvalGlobal(`new sum(1, 2)`)
+	assert.Error(err)
+
+	_, err = context.EvalGlobal(`function outer() { return fail() }; outer()`)
+	var jsErr *Error
+	require.True(t, errors.As(err, &jsErr))
+	assert.Contains(jsErr.Stack, "at fail (native)")
+	assert.Contains(jsErr.Stack, "at outer")
+}
+
+func TestContext_Constructor(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	runtime := NewRuntime()
+	defer runtime.Free()
+	context := runtime.NewContext()
+	defer context.Free()
+
+	context.Globals().Set("Point", context.Constructor("Point", 2, func(ctx *Context, this Value, args []Value) Value {
+		this.Set("x", args[0].Dup())
+		this.Set("y", args[1].Dup())
+		return ctx.Undefined()
+	}))
+
+	result, err := context.EvalGlobal(`
+Point.prototype.sum = function() { return this.x + this.y };
+class Point3D extends Point {
+	constructor(x, y, z) { super(x, y); this.z = z }
+	sum() { return super.sum() + this.z }
+}
+const p = new Point(1, 2);
+const p3 = new Point3D(1, 2, 3);
+[p.sum(), p instanceof Point, p.constructor === Point, p3.sum(), p3 instanceof Point, p3 instanceof Point3D, Point.name, Point.length]
+`)
+	require.NoError(t, err)
+	defer result.Free()
+	assert.Equal([]interface{}{int64(3), true, true, int64(6), true, true, "Point", int64(2)}, result.Interface())
+
+	_, err = context.EvalGlobal(`Point(1, 2)`)
+	assert.Error(err)
+}
+
+func TestContext_FunctionPanic(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	runtime := NewRuntime()
+	defer runtime.Free()
+	context := runtime.NewContext()
+	defer context.Free()
+
+	expected := errors.New("unexpected")
+	context.Globals().SetFunction("explode", func(ctx *Context, this Value, args []Value) Value {
+		panic(expected)
+	})
+	context.Globals().SetGoValue("divide", func(a, b int) int { return a / b })
+
+	_, err := context.EvalGlobal(`explode()`)
+	var jsErr *Error
+	require.True(t, errors.As(err, &jsErr))
+	assert.Equal("InternalError: panic: unexpected", jsErr.Cause)
+	assert.Equal(expected, jsErr.Panic)
+	assert.Contains(jsErr.GoStack, "TestContext_FunctionPanic")
+	assert.Contains(jsErr.Stack, "at explode (native)")
+
+	_, err = context.EvalGlobal(`divide(1, 0)`)
+	require.True(t, errors.As(err, &jsErr))
+	assert.Contains(jsErr.Cause, "integer divide by zero")
+	assert.NotNil(jsErr.Panic)
+
+	result, err := context.EvalGlobal(`
+let caught;
+try { explode() } catch (e) { caught = e }
+[caught instanceof InternalError, typeof caught.goStack, divide(4, 2)]
+`)
+	require.NoError(t, err)
+	defer result.Free()
+	assert.Equal([]interface{}{true, "string", int64(2)}, result.Interface())
+
+	// the instance of the panicked constructor is released
+	context.Globals().Set("Broken", context.Constructor("Broken", 0, func(ctx *Context, this Value, args []Value) Value {
+		panic("broken")
+	}))
+	_, err = context.EvalGlobal(`new Broken()`)
+	require.True(t, errors.As(err, &jsErr))
+	assert.Equal("broken", jsErr.Panic)
+
+	// the panic is reported regardless of the InternalError overwritten by script, and the message is kept
+	long := strings.Repeat("x", 300)
+	context.Globals().SetFunction("explodeLong", func(ctx *Context, this Value, args []Value) Value {
+		panic(long)
+	})
+	v, err := context.EvalGlobal(`globalThis.InternalError = function() { return { hijacked: true } }`)
+	require.NoError(t, err)
+	v.Free()
+	_, err = context.EvalGlobal(`explodeLong()`)
+	require.True(t, errors.As(err, &jsErr))
+	assert.Equal("InternalError: panic: "+long, jsErr.Cause)
+	assert.Equal(long, jsErr.Panic)
+	assert.Contains(jsErr.Stack, "at explodeLong (native)")
+}
+
+func TestRuntime_SetRepanic(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+
+	// the panic unwinds through the quickjs frames, so the values of them are leaked and abandoned
+	runtime := NewRuntime()
+	defer runtime.ForceFree()
+	runtime.SetRepanic(true)
+	context := runtime.NewContext()
+	defer context.Free()
+
+	context.Globals().SetFunction("explode", func(ctx *Context, this Value, args []Value) Value {
+		panic("explode")
+	})
+
+	assert.PanicsWithValue(t, "explode", func() { context.EvalGlobal(`explode()`) })
+}
@@ -0,0 +1,135 @@
+package quickjs
+
+// Scope track the values obtained through it and free them when the scope exits,
+// the values which should outlive the scope must be kept by Scope.Escape or Scope.Dup.
+//
+// Value.Set and Value.SetByAtom take the ownership of the value, use Scope.Set for the tracked values
+// instead, or the value will be freed twice
+type Scope struct {
+	ctx    *Context
+	values []Value
+}
+
+// Scope run fn with a new Scope, the values tracked by the scope are freed in reverse order
+// when fn returns or panics, the error of fn is returned
+func (ctx *Context) Scope(fn func(s *Scope) error) error {
+	s := &Scope{ctx: ctx}
+	defer s.free()
+	return fn(s)
+}
+
+func (s *Scope) free() {
+	for i := len(s.values) - 1; i >= 0; i-- {
+		s.values[i].Free()
+	}
+	s.values = nil
+}
+
+// Context of the scope
+func (s *Scope) Context() *Context { return s.ctx }
+
+// Track the owned value, it will be freed when the scope exits
+func (s *Scope) Track(v Value) Value {
+	if !v.global {
+		s.values = append(s.values, v)
+	}
+	return v
+}
+
+// Escape stop tracking the value, so that it outlives the scope and must be freed by the caller,
+// the value is duplicated if it is not tracked by the scope
+func (s *Scope) Escape(v Value) Value {
+	for i := len(s.values) - 1; i >= 0; i-- {
+		if s.values[i] == v {
+			s.values = append(s.values[:i], s.values[i+1:]...)
+			return v
+		}
+	}
+	return v.Dup()
+}
+
+// Dup the value which outlives the scope, it must be freed by the caller
+func (s *Scope) Dup(v Value) Value { return v.Dup() }
+
+// Globals of the context, it is owned by the context and not tracked
+func (s *Scope) Globals() Value { return s.ctx.Globals() }
+
+// Get the property of object
+func (s *Scope) Get(v Value, name string) Value { return s.Track(v.Get(name)) }
+
+// GetByUint32 get the item of array
+func (s *Scope) GetByUint32(v Value, idx uint32) Value { return s.Track(v.GetByUint32(idx)) }
+
+// Set the property of object to the value, the value is still tracked by the scope
+func (s *Scope) Set(v Value, name string, val Value) { v.Set(name, val.Dup()) }
+
+// SetByUint32 set the item of array to the value, the value is still tracked by the scope
+func (s *Scope) SetByUint32(v Value, idx uint32, val Value) { v.SetByUint32(idx, val.Dup()) }
+
+// Call the function with golang arguments converted by Context.ToJSValue, the exception is returned as error
+func (s *Scope) Call(fn Value, args ...interface{}) (Value, error) {
+	return s.CallWithContext(fn, s.ctx.Undefined(), args...)
+}
+
+// CallWithContext call the function with `this` and golang arguments, the exception is returned as error
+func (s *Scope) CallWithContext(fn Value, this Value, args ...interface{}) (Value, error) {
+	result := fn.DynamicCallWithContext(this, args...)
+	if result.IsException() {
+		return result, s.ctx.Exception()
+	}
+	return s.Track(result), nil
+}
+
+// New construct the object with golang arguments converted by Context.ToJSValue, the exception is returned as error
+func (s *Scope) New(constructor Value, args ...interface{}) (Value, error) {
+	jsArgs := make([]Value, 0, len(args))
+	for _, arg := range args {
+		jsArg, err := s.Marshal(arg)
+		if err != nil {
+			return s.ctx.Undefined(), err
+		}
+		jsArgs = append(jsArgs, jsArg)
+	}
+	result := constructor.New(jsArgs...)
+	if result.IsException() {
+		return result, s.ctx.Exception()
+	}
+	return s.Track(result), nil
+}
+
+// Eval the code in global scope
+func (s *Scope) Eval(code string) (Value, error) {
+	result, err := s.ctx.EvalGlobal(code)
+	if err != nil {
+		return result, err
+	}
+	return s.Track(result), nil
+}
+
+// ToJSValue convert golang value to tracked javascript value, see Context.ToJSValue
+func (s *Scope) ToJSValue(value interface{}) Value {
+	if v, ok := value.(Value); ok {
+		return v
+	}
+	return s.Track(s.ctx.ToJSValue(value))
+}
+
+// Marshal convert golang value to tracked javascript value, see Context.Marshal
+func (s *Scope) Marshal(value interface{}) (Value, error) {
+	if v, ok := value.(Value); ok {
+		return v, nil
+	}
+	val, err := s.ctx.Marshal(value)
+	if err != nil {
+		return val, err
+	}
+	return s.Track(val), nil
+}
+
+func (s *Scope) Object() Value                { return s.Track(s.ctx.Object()) }
+func (s *Scope) Array() Value                 { return s.Track(s.ctx.Array()) }
+func (s *Scope) String(v string) Value        { return s.Track(s.ctx.String(v)) }
+func (s *Scope) Int64(v int64) Value          { return s.Track(s.ctx.Int64(v)) }
+func (s *Scope) Float64(v float64) Value      { return s.Track(s.ctx.Float64(v)) }
+func (s *Scope) Bool(v bool) Value            { return s.Track(s.ctx.Bool(v)) }
+func (s *Scope) Function(fn JSFunction) Value { return s.Track(s.ctx.Function(fn)) }
@@ -0,0 +1,77 @@
+package quickjs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+)
+
+func TestContext_Scope(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	// the runtime asserts that all objects are freed
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	_, err := ctx.EvalGlobal(`var Point = class { constructor(x, y) { this.x = x; this.y = y } }; function sum(p) { return p.x + p.y }`)
+	require.NoError(t, err)
+
+	var escaped Value
+	err = ctx.Scope(func(s *Scope) error {
+		point, err := s.New(s.Get(s.Globals(), "Point"), 1, 2)
+		if err != nil {
+			return err
+		}
+		total, err := s.Call(s.Get(s.Globals(), "sum"), point)
+		if err != nil {
+			return err
+		}
+		assert.Equal(int64(3), total.Interface())
+
+		list := s.Array()
+		s.SetByUint32(list, 0, point)
+		s.Set(list, "label", s.String("points"))
+		s.Set(s.Globals(), "list", list)
+		assert.Equal("points", s.Get(list, "label").String())
+		assert.Equal(int64(2), s.Get(s.GetByUint32(list, 0), "y").Interface())
+
+		escaped = s.Escape(point)
+		_, err = s.Call(s.Get(s.Globals(), "missing"))
+		return err
+	})
+	assert.EqualError(err, "TypeError: not a function")
+	assert.Equal(int64(1), escaped.GetInt64("x"))
+	escaped.Free()
+
+	v, err := ctx.EvalGlobal(`list.label + list[0].x`)
+	require.NoError(t, err)
+	assert.Equal("points1", v.InterfaceAndFree())
+
+	assert.Panics(func() {
+		ctx.Scope(func(s *Scope) error {
+			s.Object()
+			panic(errors.New("panic in scope"))
+		})
+	})
+
+	err = ctx.Scope(func(s *Scope) error {
+		obj, err := s.Eval(`({ a: { b: [1, 2] } })`)
+		if err != nil {
+			return err
+		}
+		kept := s.Dup(s.Get(obj, "a"))
+		ctx.Globals().Set("kept", kept)
+		_, err = s.Eval(`throw new Error("eval failed")`)
+		return err
+	})
+	assert.EqualError(err, "Error: eval failed")
+	v, err = ctx.EvalGlobal(`kept.b.length`)
+	require.NoError(t, err)
+	assert.Equal(int64(2), v.InterfaceAndFree())
+}
@@ -0,0 +1,125 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"reflect"
+	"strings"
+)
+
+// Symbol is the golang representation of javascript symbol,
+// a new unique symbol is created when it is converted to javascript unless it is well-known or registered,
+// so the identity of unique symbols is not kept across the conversions, use quickjs.Value to keep it
+type Symbol struct {
+	Description string
+	// Registered is true for the symbol in the global symbol registry, which is created by `Symbol.for`
+	Registered bool
+	// wellKnown is true for the well-known symbols only, the unique symbols with the same description are not
+	wellKnown bool
+}
+
+var symbolType = reflect.TypeOf(Symbol{})
+
+// the well-known symbols, e.g. SymbolIterator is `Symbol.iterator`
+var (
+	SymbolAsyncIterator      = Symbol{Description: "Symbol.asyncIterator", wellKnown: true}
+	SymbolHasInstance        = Symbol{Description: "Symbol.hasInstance", wellKnown: true}
+	SymbolIsConcatSpreadable = Symbol{Description: "Symbol.isConcatSpreadable", wellKnown: true}
+	SymbolIterator           = Symbol{Description: "Symbol.iterator", wellKnown: true}
+	SymbolMatch              = Symbol{Description: "Symbol.match", wellKnown: true}
+	SymbolMatchAll           = Symbol{Description: "Symbol.matchAll", wellKnown: true}
+	SymbolReplace            = Symbol{Description: "Symbol.replace", wellKnown: true}
+	SymbolSearch             = Symbol{Description: "Symbol.search", wellKnown: true}
+	SymbolSpecies            = Symbol{Description: "Symbol.species", wellKnown: true}
+	SymbolSplit              = Symbol{Description: "Symbol.split", wellKnown: true}
+	SymbolToPrimitive        = Symbol{Description: "Symbol.toPrimitive", wellKnown: true}
+	SymbolToStringTag        = Symbol{Description: "Symbol.toStringTag", wellKnown: true}
+	SymbolUnscopables        = Symbol{Description: "Symbol.unscopables", wellKnown: true}
+)
+
+var wellKnownSymbols = map[Symbol]bool{
+	SymbolAsyncIterator:      true,
+	SymbolHasInstance:        true,
+	SymbolIsConcatSpreadable: true,
+	SymbolIterator:           true,
+	SymbolMatch:              true,
+	SymbolMatchAll:           true,
+	SymbolReplace:            true,
+	SymbolSearch:             true,
+	SymbolSpecies:            true,
+	SymbolSplit:              true,
+	SymbolToPrimitive:        true,
+	SymbolToStringTag:        true,
+	SymbolUnscopables:        true,
+}
+
+// IsWellKnown return true if s is one of the well-known symbols like `Symbol.iterator`
+func (s Symbol) IsWellKnown() bool { return s.wellKnown && wellKnownSymbols[s] }
+
+func (s Symbol) String() string { return "Symbol(" + s.Description + ")" }
+
+// Symbol create a new unique javascript symbol with description, same as `Symbol(description)`
+func (ctx *Context) Symbol(description string) Value {
+	return ctx.callSymbolFunction(intrinsicSymbol, description)
+}
+
+// callSymbolFunction call the builtin `Symbol` function or its method like `Symbol.for` with the description
+func (ctx *Context) callSymbolFunction(index int, description string) Value {
+	symbolFunc := ctx.intrinsic(intrinsicSymbol)
+	defer symbolFunc.Free()
+	jsDescription := ctx.String(description)
+	defer jsDescription.Free()
+	return ctx.callIntrinsic(index, symbolFunc, jsDescription)
+}
+
+// wellKnownSymbol return the well-known symbol like `Symbol.iterator` by name, the properties of
+// the builtin `Symbol` are not writable, so they could not be changed by script
+func (ctx *Context) wellKnownSymbol(name string) Value {
+	symbolFunc := ctx.intrinsic(intrinsicSymbol)
+	defer symbolFunc.Free()
+	return symbolFunc.Get(name)
+}
+
+// symbol convert Symbol to javascript symbol
+func (ctx *Context) symbol(s Symbol) Value {
+	switch {
+	case s.IsWellKnown():
+		return ctx.wellKnownSymbol(strings.TrimPrefix(s.Description, "Symbol."))
+	case s.Registered:
+		return ctx.callSymbolFunction(intrinsicSymbolFor, s.Description)
+	default:
+		return ctx.Symbol(s.Description)
+	}
+}
+
+// Symbol return the golang representation of javascript symbol, the well-known symbols are identified
+// by the `Symbol.<name>` values rather than the descriptions
+func (v Value) Symbol() Symbol {
+	description := v.ctx.callIntrinsic(intrinsicSymbolDescription, v)
+	defer description.Free()
+	s := Symbol{}
+	if description.IsString() {
+		s.Description = description.String()
+	}
+
+	if name := strings.TrimPrefix(s.Description, "Symbol."); name != s.Description && wellKnownSymbols[Symbol{Description: s.Description, wellKnown: true}] {
+		wellKnown := v.ctx.wellKnownSymbol(name)
+		defer wellKnown.Free()
+		// the symbols are strictly equal if they are the same atom
+		s.wellKnown = wellKnown.IsSymbol() && C.GetValuePtr(wellKnown.ref) == C.GetValuePtr(v.ref)
+	}
+	symbolFunc := v.ctx.intrinsic(intrinsicSymbol)
+	defer symbolFunc.Free()
+	key := v.ctx.callIntrinsic(intrinsicSymbolKeyFor, symbolFunc, v)
+	defer key.Free()
+	s.Registered = key.IsString()
+
+	return s
+}
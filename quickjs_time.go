@@ -0,0 +1,65 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -D_GNU_SOURCE
+#cgo CFLAGS: -DCONFIG_BIGNUM
+#cgo CFLAGS: -fno-asynchronous-unwind-tables
+#cgo LDFLAGS: -lm -lpthread
+
+#include "bridge.h"
+*/
+import "C"
+import (
+	"math"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Date create javascript Date with millisecond precision,
+// javascript Date is a timestamp without timezone, so the location of t is dropped
+func (ctx *Context) Date(t time.Time) Value {
+	ms := float64(t.Unix())*1e3 + float64(t.Nanosecond()/int(time.Millisecond))
+	return ctx.newValue(C.JS_NewDate(ctx.ref, C.double(ms)))
+}
+
+// Time return the time of javascript Date in the local location (same as time.Unix),
+// the number will be treated as milliseconds since epoch, and the invalid Date will be zero time
+func (v Value) Time() time.Time {
+	ms := v.Float64()
+	if math.IsNaN(ms) || math.IsInf(ms, 0) {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(ms / 1e3)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e3))*int64(time.Millisecond))
+}
+
+// Duration return the number as milliseconds of time.Duration
+func (v Value) Duration() time.Duration {
+	return millisecondsToDuration(v.Float64())
+}
+
+// durationToMilliseconds represent the time.Duration as javascript number of milliseconds
+func durationToMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func millisecondsToDuration(ms float64) time.Duration {
+	return time.Duration(math.Round(ms * float64(time.Millisecond)))
+}
+
+// timeDecodeHook convert the javascript numbers to time.Duration as milliseconds for Value.Decode
+func timeDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != durationType {
+		return data, nil
+	}
+	switch n := data.(type) {
+	case int64:
+		return millisecondsToDuration(float64(n)), nil
+	case float64:
+		return millisecondsToDuration(n), nil
+	}
+	return data, nil
+}
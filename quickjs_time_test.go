@@ -0,0 +1,74 @@
+package quickjs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	stdruntime "runtime"
+	"testing"
+	"time"
+)
+
+type DemoSchedule struct {
+	Name     string
+	StartAt  time.Time
+	Interval time.Duration
+	EndAt    *time.Time
+}
+
+func TestContext_Date(t *testing.T) {
+	stdruntime.LockOSThread()
+	defer stdruntime.UnlockOSThread()
+	assert := assert.New(t)
+
+	r := NewRuntime()
+	defer r.Free()
+	ctx := r.NewContext()
+	defer ctx.Free()
+
+	startAt := time.Date(2021, 3, 4, 5, 6, 7, 891234567, time.UTC)
+	truncated := startAt.Truncate(time.Millisecond)
+
+	date := ctx.ToJSValue(startAt)
+	assert.True(date.IsDate())
+	assert.Equal("2021-03-04T05:06:07.891Z", date.ToJsonString()[1:25])
+	assert.True(truncated.Equal(date.InterfaceAndFree().(time.Time)))
+
+	before := ctx.ToJSValue(time.Date(1960, 1, 1, 0, 0, 0, 500000000, time.UTC))
+	assert.True(time.Date(1960, 1, 1, 0, 0, 0, 500000000, time.UTC).Equal(before.Time()))
+	before.Free()
+
+	invalid, err := ctx.EvalGlobal(`new Date(NaN)`)
+	require.NoError(t, err)
+	assert.True(invalid.Time().IsZero())
+	invalid.Free()
+
+	ctx.Globals().SetGoValue("schedule", DemoSchedule{Name: "job", StartAt: startAt, Interval: 1500 * time.Millisecond})
+	v, err := ctx.EvalGlobal(`[schedule.StartAt instanceof Date, schedule.StartAt.getTime(), schedule.Interval, schedule.EndAt]`)
+	require.NoError(t, err)
+	assert.Equal([]interface{}{true, truncated.UnixNano() / 1e6, int64(1500), nil}, v.InterfaceAndFree())
+
+	ctx.Globals().SetGoValue("next", func(at time.Time, interval time.Duration) time.Time {
+		return at.Add(interval)
+	})
+	v, err = ctx.EvalGlobal(`next(new Date(Date.UTC(2021, 0, 1)), 60000).toISOString()`)
+	require.NoError(t, err)
+	assert.Equal("2021-01-01T00:01:00.000Z", v.InterfaceAndFree())
+
+	// the arguments are decoded as Value.Decode does
+	v, err = ctx.EvalGlobal(`next("2021-01-01T00:00:00Z", "1m").toISOString()`)
+	require.NoError(t, err)
+	assert.Equal("2021-01-01T00:01:00.000Z", v.InterfaceAndFree())
+	_, err = ctx.EvalGlobal(`next("tomorrow", 0)`)
+	require.Error(t, err)
+	assert.Contains(err.Error(), "cannot parse")
+
+	v, err = ctx.EvalGlobal(`({ Name: "job", StartAt: new Date(Date.UTC(2021, 0, 1)), Interval: 250, EndAt: new Date(Date.UTC(2021, 0, 2)) })`)
+	require.NoError(t, err)
+	defer v.Free()
+	schedule := &DemoSchedule{}
+	assert.NoError(v.Decode(schedule))
+	assert.True(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Equal(schedule.StartAt))
+	assert.Equal(250*time.Millisecond, schedule.Interval)
+	require.NotNil(t, schedule.EndAt)
+	assert.True(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC).Equal(*schedule.EndAt))
+}
@@ -11,7 +11,6 @@ package quickjs
 import "C"
 import (
 	"errors"
-	"github.com/mitchellh/mapstructure"
 	"math/big"
 	"reflect"
 	"unsafe"
@@ -46,6 +45,7 @@ type Value struct {
 func (v Value) Free() {
 
 	if !IsUndefinedOrNull(v.ref) && GetRefCount(v.ctx.ref, v.ref) > 0 {
+		v.untrack()
 		C.JS_FreeValue(v.ctx.ref, v.ref)
 	}
 
@@ -70,16 +70,10 @@ func (v Value) New(args ...Value) Value {
 			jsArgs = append(jsArgs, goArg.ref)
 		}
 
-		return Value{
-			ctx: v.ctx,
-			ref: C.JS_CallConstructor(v.ctx.ref, v.ref, C.int(len(args)), &jsArgs[0]),
-		}
+		return v.ctx.newValue(C.JS_CallConstructor(v.ctx.ref, v.ref, C.int(len(args)), &jsArgs[0]))
 	}
 
-	return Value{
-		ctx: v.ctx,
-		ref: C.JS_CallConstructor(v.ctx.ref, v.ref, C.int(len(args)), nil),
-	}
+	return v.ctx.newValue(C.JS_CallConstructor(v.ctx.ref, v.ref, C.int(len(args)), nil))
 
 }
 
@@ -88,15 +82,24 @@ func (v Value) DynamicCall(args ...interface{}) Value {
 	return v.DynamicCallWithContext(v.ctx.Undefined(), args...)
 }
 
-// DynamicCallWithContext function using go objects with `this`
+// DynamicCallWithContext function using go objects with `this`,
+// a RangeError will be thrown (and returned) if the conversion of arguments exceeds the limits
 func (v Value) DynamicCallWithContext(goThisArgs interface{}, args ...interface{}) Value {
-	var thisArg Value
 	var jsArgs []Value
 
-	thisArg = v.ctx.ToJSValue(goThisArgs)
+	thisArg, err := v.ctx.Marshal(goThisArgs)
+	if err != nil {
+		return v.ctx.ThrowRangeError("%v", err)
+	}
+	if thisArg != goThisArgs {
+		defer thisArg.Free()
+	}
 
 	for _, arg := range args {
-		jsArg := v.ctx.ToJSValue(arg)
+		jsArg, err := v.ctx.Marshal(arg)
+		if err != nil {
+			return v.ctx.ThrowRangeError("%v", err)
+		}
 		if jsArg != arg {
 			defer jsArg.Free()
 		}
@@ -120,16 +123,10 @@ func (v Value) CallWithContext(thisArg Value, args ...Value) Value {
 			jsArgs = append(jsArgs, goArg.ref)
 		}
 
-		return Value{
-			ctx: v.ctx,
-			ref: C.JS_Call(v.ctx.ref, v.ref, thisArg.ref, C.int(len(args)), &jsArgs[0]),
-		}
+		return v.ctx.newValue(C.JS_Call(v.ctx.ref, v.ref, thisArg.ref, C.int(len(args)), &jsArgs[0]))
 	}
 
-	return Value{
-		ctx: v.ctx,
-		ref: C.JS_Call(v.ctx.ref, v.ref, thisArg.ref, C.int(len(args)), nil),
-	}
+	return v.ctx.newValue(C.JS_Call(v.ctx.ref, v.ref, thisArg.ref, C.int(len(args)), nil))
 }
 
 func (v Value) Int64() int64 {
@@ -229,15 +226,15 @@ func (v Value) GetStringByUint32(idx uint32) string {
 }
 
 func (v Value) SetByAtom(atom Atom, val Value) {
-	C.JS_SetProperty(v.ctx.ref, v.ref, atom.ref, val.ref)
+	C.JS_SetProperty(v.ctx.ref, v.ref, atom.ref, val.transfer())
 }
 
 func (v Value) SetByInt64(idx int64, val Value) {
-	C.JS_SetPropertyInt64(v.ctx.ref, v.ref, C.int64_t(idx), val.ref)
+	C.JS_SetPropertyInt64(v.ctx.ref, v.ref, C.int64_t(idx), val.transfer())
 }
 
 func (v Value) SetByUint32(idx uint32, val Value) {
-	C.JS_SetPropertyUint32(v.ctx.ref, v.ref, C.uint32_t(idx), val.ref)
+	C.JS_SetPropertyUint32(v.ctx.ref, v.ref, C.uint32_t(idx), val.transfer())
 }
 
 func (v Value) Len() int64 { return v.Get("length").Int64() }
@@ -263,6 +260,17 @@ func (v Value) HasProperty(name string) bool {
 	return C.JS_HasProperty(v.ctx.ref, v.ref, nameAtom.ref) == 1
 }
 
+// hasOwnProperty report whether the object has the property with name, the inherited properties are excluded
+func (v Value) hasOwnProperty(name string) (bool, error) {
+	nameAtom := v.ctx.Atom(name)
+	defer nameAtom.Free()
+	ret := C.JS_GetOwnProperty(v.ctx.ref, nil, v.ref, nameAtom.ref)
+	if ret < 0 {
+		return false, v.ctx.Exception()
+	}
+	return ret == 1, nil
+}
+
 // DeleteProperty property
 func (v Value) DeleteProperty(name string) {
 	nameAtom := v.ctx.Atom(name)
@@ -271,29 +279,57 @@ func (v Value) DeleteProperty(name string) {
 }
 
 func (v Value) SetFunction(name string, fn JSFunction) {
-	v.Set(name, v.ctx.Function(fn))
+	v.Set(name, v.ctx.NamedFunction(name, 0, fn))
+}
+
+// defineProperty with flags, the val will be freed
+func (v Value) defineProperty(name string, val Value, flags C.int) {
+	nameAtom := v.ctx.Atom(name)
+	defer nameAtom.Free()
+	C.JS_DefinePropertyValue(v.ctx.ref, v.ref, nameAtom.ref, val.transfer(), flags)
 }
 
 type Error struct {
 	Cause string
 	Stack string
+	// Panic is the recovered value if the error is raised by a panic of golang function
+	Panic interface{}
+	// GoStack is the golang stack of the panic
+	GoStack string
+	// Err is the golang error thrown by Context.ThrowError
+	Err error
 }
 
 func (err Error) Error() string { return err.Cause }
 
+func (err Error) Unwrap() error { return err.Err }
+
 func (v Value) Error() error {
 	if !v.IsError() {
 		return nil
 	}
-	cause := v.String()
+	err := &Error{Cause: v.String()}
 
 	stack := v.Get("stack")
 	defer stack.Free()
+	if !stack.IsUndefined() {
+		err.Stack = stack.String()
+	}
+
+	goError := v.Get("goError")
+	defer goError.Free()
+	if e, ok := goError.goValue(); ok {
+		err.Err, _ = e.(error)
+	}
 
-	if stack.IsUndefined() {
-		return &Error{Cause: cause}
+	goPanic := v.Get("goPanic")
+	defer goPanic.Free()
+	if r, ok := goPanic.goValue(); ok {
+		err.Panic = r
+		err.GoStack = v.GetString("goStack")
 	}
-	return &Error{Cause: cause, Stack: stack.String()}
+
+	return err
 }
 func IsUndefinedOrNull(ref C.JSValue) bool {
 	return ref.tag == JsTagNULL || ref.tag == JsTagUNDEFINED
@@ -324,6 +360,12 @@ func (v Value) IsObject() bool {
 	return false
 }
 func (v Value) IsArray() bool       { return C.JS_IsArray(v.ctx.ref, v.ref) == 1 }
+func (v Value) IsDate() bool        { return C.JS_IsDate(v.ref) == 1 }
+func (v Value) IsArrayBuffer() bool { return C.JS_IsArrayBuffer(v.ref) == 1 }
+func (v Value) IsTypedArray() bool  { return C.JS_GetTypedArrayType(v.ref) >= 0 }
+func (v Value) IsDataView() bool    { return C.JS_IsDataView(v.ref) == 1 }
+func (v Value) IsMap() bool         { return C.JS_IsMap(v.ref) == 1 }
+func (v Value) IsSet() bool         { return C.JS_IsSet(v.ref) == 1 }
 func (v Value) IsError() bool       { return C.JS_IsError(v.ctx.ref, v.ref) == 1 }
 func (v Value) IsFunction() bool    { return C.JS_IsFunction(v.ctx.ref, v.ref) == 1 }
 func (v Value) IsConstructor() bool { return C.JS_IsConstructor(v.ctx.ref, v.ref) == 1 }
@@ -335,124 +377,91 @@ type PropertyEnum struct {
 
 func (p PropertyEnum) String() string { return p.Atom.String() }
 
-// Decode js Value to golang struct
-func (v Value) Decode(target interface{}) {
-	mapstructure.Decode(v.Interface(), target)
-}
-
 // Interface return golang value with correct type (with interface{} any type)
 func (v Value) InterfaceAndFree() interface{} {
 	defer v.Free()
 	return v.Interface()
 }
 
-// Interface return golang value with correct type (with interface{} any type)
+// Interface return golang value with correct type (with interface{} any type),
+// nil will be returned if the conversion exceeds the limits, see Value.Export
 func (v Value) Interface() interface{} {
-	if v.IsNumber() {
-		if v.IsBigInt() {
-			return v.BigInt()
+	goValue, _ := v.Export()
+	return goValue
+}
+
+// ToReflectValue used in native function call processing
+// must provide a reflect.Type to check and return the reflect.Value instance,
+// the numbers overflowing the type and the values failed to decode return error,
+// the quickjs.Value (including the nested Value fields) are not duplicated, they are valid until v is freed
+func (v Value) ToReflectValue(rType reflect.Type) (reflect.Value, error) {
+
+	// quickjs.Value will be passed through
+	switch rType {
+	case valueType:
+		return reflect.ValueOf(v), nil
+	case timeType, durationType:
+		// decoded as Value.Decode does, e.g. the RFC3339 string to time.Time, the invalid values return error
+		return v.decodeReflectValue(rType)
+	}
+
+	if v.ctx.customDecoding(rType) {
+		return v.decodeReflectValue(rType)
+	}
+
+	switch rType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if v.isNumeric() {
+			return v.decodeReflectValue(rType)
 		}
-		if v.IsBigFloat() {
-			return v.BigFloat()
+		// convert the other values as javascript does, e.g. `"1"` to 1
+		if rType.Kind() == reflect.Float32 || rType.Kind() == reflect.Float64 {
+			return reflect.ValueOf(v.Float64()).Convert(rType), nil
 		}
-		if v.IsBigDecimal() {
-			return v.BigFloat()
+		return reflect.ValueOf(v.Int64()).Convert(rType), nil
+	case reflect.Bool:
+		return reflect.ValueOf(v.Bool()).Convert(rType), nil
+	case reflect.String:
+		return reflect.ValueOf(v.String()).Convert(rType), nil
+	case reflect.Ptr:
+		if v.IsUndefined() || v.IsNull() {
+			return reflect.Zero(rType), nil
 		}
-		if v.IsIntNumber() {
-			return v.Int64()
+		if goValue, ok := v.goClassInstance(); ok && reflect.TypeOf(goValue) == rType {
+			return reflect.ValueOf(goValue), nil
 		}
-		return v.Float64()
-	}
-	if v.IsString() {
-		return v.String()
-	}
-	if v.IsUndefined() || v.IsNull() {
-		return nil
-	}
-	if v.IsBool() {
-		return v.Bool()
-	}
-	if v.IsError() {
-		return v.Error()
-	}
-
-	if v.IsArray() {
-		var rt []interface{}
-		arrayLen := v.Len()
-		for idx := int64(0); idx < arrayLen; idx++ {
-			rt = append(rt, v.GetByUint32(uint32(idx)).InterfaceAndFree())
+		ptr := reflect.New(rType.Elem())
+		elem, err := v.ToReflectValue(rType.Elem())
+		if err != nil {
+			return ptr, err
 		}
-		return rt
-	}
-
-	// function also will be an object, just return function firstly
-	if v.IsFunction() {
-		return func(args ...interface{}) interface{} {
-			var jsArgs []Value
-			for arg := range args {
-				jsArgs = append(jsArgs, v.ctx.ToJSValue(arg))
-			}
-			result := v.Call(jsArgs...)
-
-			// free all generated js args
-			for _, jsArg := range jsArgs {
-				jsArg.Free()
-			}
-
-			if result.IsException() {
-				return v.ctx.Exception()
-			}
-
-			return result.InterfaceAndFree()
+		if elem.IsValid() && elem.Type().AssignableTo(rType.Elem()) {
+			ptr.Elem().Set(elem)
 		}
-	}
-
-	if v.IsObject() {
-
-		rt := map[string]interface{}{}
-		if names, err := v.PropertyNames(); err == nil {
-			for _, name := range names {
-				propertyKey := name.String()
-				propertyValue := v.Get(propertyKey)
-				if !propertyValue.IsUndefined() {
-					rt[propertyKey] = propertyValue.InterfaceAndFree()
-				}
-			}
+		return ptr, nil
+	case reflect.Interface:
+		goValue, err := v.Export()
+		if goValue == nil {
+			return reflect.Zero(rType), err
 		}
-		return rt
+		return reflect.ValueOf(goValue), err
+	case reflect.Struct, reflect.Slice, reflect.Map, reflect.Array:
+		return v.decodeReflectValue(rType)
 	}
 
-	return nil
-}
+	return reflect.ValueOf(v.Interface()), nil
 
-// ToReflectValue used in native function call processing
-// must provide a reflect.Type to check and return the reflect.Value instance
-func (v Value) ToReflectValue(rType reflect.Type) reflect.Value {
-
-	switch rType.Kind() {
-	case reflect.Int64:
-		return reflect.ValueOf(v.Int64())
-	case reflect.Int32:
-		return reflect.ValueOf(v.Int32())
-	case reflect.Int16:
-		return reflect.ValueOf(int16(v.Int64()))
-	case reflect.Int8:
-		return reflect.ValueOf(int8(v.Int64()))
-	case reflect.Int:
-		return reflect.ValueOf(int(v.Int64()))
-	case reflect.Float32:
-		return reflect.ValueOf(float32(v.Float64()))
-	case reflect.Float64:
-		return reflect.ValueOf(v.Float64())
-	case reflect.Struct, reflect.Slice:
-		reflectInstance := reflect.New(rType)
-		instance := reflectInstance.Interface()
-		v.Decode(instance)
-		return reflect.Indirect(reflect.ValueOf(instance))
-	}
-
-	return reflect.ValueOf(v.Interface())
+}
 
+// decodeReflectValue decode the value to new instance of rType
+func (v Value) decodeReflectValue(rType reflect.Type) (reflect.Value, error) {
+	instance := reflect.New(rType)
+	d := v.newDecoder(DecodeOptions{})
+	d.borrow = true
+	err := d.decode(v, instance.Elem(), "")
+	return instance.Elem(), err
 }
 
 type JsType = string
@@ -501,10 +510,7 @@ func (v Value) ToJsonString() string {
 	undefined := v.ctx.Undefined()
 	defer undefined.Free()
 
-	jsonStr := Value{
-		ref: C.JS_JSONStringify(v.ctx.ref, v.ref, undefined.ref, undefined.ref),
-		ctx: v.ctx,
-	}
+	jsonStr := v.ctx.newValue(C.JS_JSONStringify(v.ctx.ref, v.ref, undefined.ref, undefined.ref))
 	defer jsonStr.Free()
 	return jsonStr.String()
 }
@@ -512,10 +518,7 @@ func (v Value) ToJsonString() string {
 // Dup value instance avoid freed by quickjs
 // so user MUST manually free it
 func (v Value) Dup() Value {
-	return Value{
-		ctx: v.ctx,
-		ref: C.JS_DupValue(v.ctx.ref, v.ref),
-	}
+	return v.ctx.newValue(C.JS_DupValue(v.ctx.ref, v.ref))
 }
 
 // PropertyNames of object, includes prototype
@@ -321,19 +321,23 @@ func TestValue_ToReflectValue(t *testing.T) {
 	Global.Set("v1", ctx.Int32(42))
 	v1 := Global.Get("v1")
 	defer v1.Free()
-	reflectV1 := v1.ToReflectValue(reflect.TypeOf(int32(42)))
+	reflectV1, err := v1.ToReflectValue(reflect.TypeOf(int32(42)))
+	assert.NoError(err)
 	assert.Equal(int32(42), reflectV1.Interface())
 
 	ctx.EvalGlobal("var v2 = {a:1,b:'2'}")
 	v2 := Global.Get("v2")
 	defer v2.Free()
-	reflectV2 := v2.ToReflectValue(reflect.TypeOf(ReflectValueTestStruct{}))
+	var reflectV2, reflectV3 reflect.Value
+	reflectV2, err = v2.ToReflectValue(reflect.TypeOf(ReflectValueTestStruct{}))
+	assert.NoError(err)
 	assert.Equal(ReflectValueTestStruct{1, "2"}, reflectV2.Interface())
 
 	ctx.EvalGlobal("var v3 = [1,2,3]")
 	v3 := Global.Get("v3")
 	defer v3.Free()
-	reflectV3 := v3.ToReflectValue(reflect.TypeOf([]int32{}))
+	reflectV3, err = v3.ToReflectValue(reflect.TypeOf([]int32{}))
+	assert.NoError(err)
 	assert.Equal([]int32{1, 2, 3}, reflectV3.Interface())
 }
 
@@ -20,11 +20,34 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"unicode"
 )
 
 var keyMapStructure = "mapstructure"
 
+var keyQuickJS = "quickjs"
+
+var keyJSON = "json"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var valueType = reflect.TypeOf(Value{})
+var thisType = reflect.TypeOf(This{})
+var contextType = reflect.TypeOf(&Context{})
+
+// isErrorType of the golang function result, the value must be nillable
+func isErrorType(t reflect.Type) bool {
+	return (t.Kind() == reflect.Interface || t.Kind() == reflect.Ptr) && t.Implements(errorType)
+}
+
+// isNilError report whether the error result is nil, includes the nil pointer wrapped in the error interface
+func isNilError(v reflect.Value) bool {
+	if v.IsNil() {
+		return true
+	}
+	return v.Kind() == reflect.Interface && v.Elem().Kind() == reflect.Ptr && v.Elem().IsNil()
+}
+
 func isExportedName(name string) bool {
 
 	if len(name) > 0 {
@@ -35,12 +58,123 @@ func isExportedName(name string) bool {
 	return false
 }
 
-func getFieldName(field reflect.StructField) string {
-	tag := field.Tag.Get(keyMapStructure)
-	if len(tag) > 0 {
-		return tag
+// parseFieldTag lookup the quickjs, mapstructure and json tags in order, return the first name not empty,
+// and the options of the tags looked up, e.g. the name of `quickjs:",readonly" json:"name"` is "name"
+func parseFieldTag(field reflect.StructField) (name string, options []string, tagged bool) {
+	for _, key := range []string{keyQuickJS, keyMapStructure, keyJSON} {
+		if tag, ok := field.Tag.Lookup(key); ok {
+			parts := strings.Split(tag, ",")
+			options = append(options, parts[1:]...)
+			tagged = true
+			if len(parts[0]) > 0 {
+				return parts[0], options, tagged
+			}
+		}
+	}
+	return "", options, tagged
+}
+
+func hasTagOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// NamingPolicy convert the golang name of methods and untagged fields to the javascript property name
+type NamingPolicy func(name string) string
+
+// LowerCamelCase naming policy, e.g. `Name` to `name`, `UserID` to `userID` and `URLPath` to `urlPath`
+func LowerCamelCase(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		// keep the last upper case letter of the leading acronym, which starts the next word
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// structField is the field of struct visible in javascript
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	readonly  bool
+}
+
+// structFields return the visible fields of struct type,
+// the fields of embedded struct without tag name are flattened as golang promotes them,
+// and the names of untagged fields are converted by the naming policy
+func structFields(t reflect.Type, naming NamingPolicy) []structField {
+	var fields []structField
+	var embedded []structField
+	names := map[string]bool{}
+
+	for fIndex := 0; fIndex < t.NumField(); fIndex++ {
+		field := t.Field(fIndex)
+		name, options, _ := parseFieldTag(field)
+		if name == "-" && len(options) == 0 {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && (len(name) == 0 || hasTagOption(options, "squash")) {
+			for _, f := range structFields(fieldType, naming) {
+				f.index = append([]int{fIndex}, f.index...)
+				embedded = append(embedded, f)
+			}
+			continue
+		}
+
+		if !isExportedName(field.Name) {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+			if naming != nil {
+				name = naming(name)
+			}
+		}
+		names[name] = true
+		fields = append(fields, structField{
+			name:      name,
+			index:     []int{fIndex},
+			omitEmpty: hasTagOption(options, "omitempty"),
+			readonly:  hasTagOption(options, "readonly"),
+		})
 	}
-	return field.Name
+
+	// the outer fields shadow the fields of embedded struct
+	for _, f := range embedded {
+		if !names[f.name] {
+			names[f.name] = true
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// fieldByIndex same as reflect.Value.FieldByIndex, but return false instead of panic for the nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fIndex := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fIndex)
+	}
+	return v, true
 }
 
 func GetRefCount(ctx *C.JSContext, value C.JSValue) int64 {
@@ -48,6 +182,11 @@ func GetRefCount(ctx *C.JSContext, value C.JSValue) int64 {
 	return rt
 }
 
+// isOptionalType of the golang function parameter, the trailing optional parameters could be omitted in javascript
+func isOptionalType(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface || t == valueType
+}
+
 // GoJSObject shortcut
 type GoJSObject map[string]interface{}
 
@@ -3,6 +3,7 @@ package quickjs
 import (
 	"github.com/stretchr/testify/assert"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -19,14 +20,41 @@ func Test_isExportedName(t *testing.T) {
 type T1 struct {
 	a string
 	B string `mapstructure:"b"`
+	C string `json:"c,omitempty"`
+	D string `quickjs:",readonly" json:"d"`
+	E string `quickjs:"e" json:"other"`
 }
 
-func Test_getFieldName(t *testing.T) {
+func Test_parseFieldTag(t *testing.T) {
+	assert := assert.New(t)
+	tp := reflect.TypeOf(T1{})
+	parse := func(i int) []interface{} {
+		name, options, tagged := parseFieldTag(tp.Field(i))
+		return []interface{}{name, strings.Join(options, ","), tagged}
+	}
+	assert.Equal([]interface{}{"", "", false}, parse(0))
+	assert.Equal([]interface{}{"b", "", true}, parse(1))
+	assert.Equal([]interface{}{"c", "omitempty", true}, parse(2))
+	assert.Equal([]interface{}{"d", "readonly", true}, parse(3))
+	assert.Equal([]interface{}{"e", "", true}, parse(4))
+}
+
+func Test_isErrorType(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isErrorType(reflect.TypeOf((*error)(nil)).Elem()))
+	assert.True(isErrorType(reflect.TypeOf(&demoError{})))
+	assert.False(isErrorType(reflect.TypeOf("")))
+	assert.False(isErrorType(reflect.TypeOf(T1{})))
+}
+
+func TestLowerCamelCase(t *testing.T) {
 	assert := assert.New(t)
-	t1 := T1{}
-	v := reflect.ValueOf(t1)
-	tp := v.Type()
-	assert.Equal("a", getFieldName(tp.Field(0)))
-	assert.Equal("b", getFieldName(tp.Field(1)))
 
+	assert.Equal("name", LowerCamelCase("Name"))
+	assert.Equal("userID", LowerCamelCase("UserID"))
+	assert.Equal("urlPath", LowerCamelCase("URLPath"))
+	assert.Equal("id", LowerCamelCase("ID"))
+	assert.Equal("a", LowerCamelCase("a"))
+	assert.Equal("", LowerCamelCase(""))
 }